@@ -1,9 +1,14 @@
 package configuration
 
 import (
+	"flag"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetConfiguration(t *testing.T) {
@@ -14,18 +19,172 @@ func TestGetConfiguration(t *testing.T) {
 			Leader:   "",
 			Rotation: 1000000000,
 			Scheduler: SchedulerConfiguration{
-				Mode: "linear",
+				Mode:        "linear",
+				Probability: 0.25,
+				K:           1,
 			},
+			Order: OrderConfiguration{
+				Mode: "alphabetical",
+			},
+			Protocol: "states",
 		},
 		EndpointConfiguration: EndpointConfiguration{
-			LEDPath: "/sys/class/leds/led1",
+			LEDPath:  "/sys/class/leds/led1",
+			Fallback: FallbackConfiguration{Mode: "none", Rotations: 5},
 		},
 		K8SConfiguration: K8SConfiguration{
 			LockName:  "ledswitcher",
 			Namespace: "default",
 		},
+		RegistryConfiguration: RegistryConfiguration{
+			RegistrationInterval: 10 * time.Second,
+			NodeExpiration:       time.Minute,
+			CleanupInterval:      10 * time.Minute,
+		},
 	}
-	got := GetConfiguration()
+	got, err := GetConfiguration()
+	require.NoError(t, err)
 	got.NodeName = ""
 	assert.Equal(t, want, got)
 }
+
+func TestLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	passwordFile := filepath.Join(tmpDir, "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("file-password\n"), 0600))
+
+	tests := []struct {
+		name         string
+		args         []string
+		env          map[string]string
+		wantPassword string
+		wantMode     string
+		wantErr      assert.ErrorAssertionFunc
+	}{
+		{
+			name:     "defaults",
+			wantMode: "linear",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:         "flags",
+			args:         []string{"-mode=binary", "-redis.password=flag-password", "-redis.password-file=" + passwordFile},
+			env:          map[string]string{"LEDSWITCHER_MODE": "random", "LEDSWITCHER_REDIS_PASSWORD": "env-password"},
+			wantPassword: "flag-password",
+			wantMode:     "binary",
+			wantErr:      assert.NoError,
+		},
+		{
+			name:         "environment",
+			args:         []string{"-redis.password-file=" + passwordFile},
+			env:          map[string]string{"LEDSWITCHER_MODE": "random", "LEDSWITCHER_REDIS_PASSWORD": "env-password"},
+			wantPassword: "env-password",
+			wantMode:     "random",
+			wantErr:      assert.NoError,
+		},
+		{
+			name:         "file",
+			env:          map[string]string{"LEDSWITCHER_REDIS_PASSWORD_FILE": passwordFile},
+			wantPassword: "file-password",
+			wantMode:     "linear",
+			wantErr:      assert.NoError,
+		},
+		{
+			name:    "missing file",
+			args:    []string{"-redis.password-file=" + filepath.Join(tmpDir, "missing")},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "invalid environment",
+			env:     map[string]string{"LEDSWITCHER_ROTATION": "invalid"},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), tt.args)
+			tt.wantErr(t, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tt.wantPassword, cfg.RedisConfiguration.Password)
+			assert.Equal(t, tt.wantMode, cfg.LeaderConfiguration.Scheduler.Mode)
+		})
+	}
+}
+
+func TestLoad_APIToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("file-token\n"), 0600))
+
+	cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-api.token-file=" + tokenFile})
+	require.NoError(t, err)
+	assert.Equal(t, "file-token", cfg.APIConfiguration.Token)
+
+	cfg, err = Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-api.token=flag-token", "-api.token-file=" + tokenFile})
+	require.NoError(t, err)
+	assert.Equal(t, "flag-token", cfg.APIConfiguration.Token)
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "LEDSWITCHER_REDIS_PASSWORD_FILE", EnvName("redis.password-file"))
+	assert.Equal(t, "LEDSWITCHER_LED_PATH", EnvName("led-path"))
+}
+
+func TestLoad_ConfigFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+debug: true
+leader:
+  rotation: 500ms
+  scheduler:
+    mode: binary
+redis:
+  addr: redis:6379
+  tls:
+    enabled: true
+`), 0600))
+
+	cfg, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config=" + configFile, "-mode=random"})
+	require.NoError(t, err)
+	assert.True(t, cfg.Debug)
+	assert.Equal(t, 500*time.Millisecond, cfg.LeaderConfiguration.Rotation)
+	assert.Equal(t, "random", cfg.LeaderConfiguration.Scheduler.Mode)
+	assert.Equal(t, "redis:6379", cfg.RedisConfiguration.Addr)
+	assert.True(t, cfg.RedisConfiguration.TLS.Enabled)
+	assert.Equal(t, "/sys/class/leds/led1", cfg.EndpointConfiguration.LEDPath)
+
+	require.NoError(t, os.WriteFile(configFile, []byte("invalid: true\n"), 0600))
+	_, err = Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config=" + configFile})
+	assert.Error(t, err)
+
+	_, err = Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config=" + filepath.Join(tmpDir, "missing.yaml")})
+	assert.Error(t, err)
+}
+
+func TestLabels(t *testing.T) {
+	var labels Labels
+	require.NoError(t, labels.Set("rack=a, position=2,"))
+	assert.Equal(t, Labels{"rack": "a", "position": "2"}, labels)
+	assert.Equal(t, "position=2,rack=a", labels.String())
+	assert.Error(t, labels.Set("rack"))
+	assert.Error(t, labels.Set("=a"))
+}
+
+func TestStringList(t *testing.T) {
+	var list StringList
+	require.NoError(t, list.Set("pi1, pi2,,pi10"))
+	assert.Equal(t, StringList{"pi1", "pi2", "pi10"}, list)
+	assert.Equal(t, "pi1,pi2,pi10", list.String())
+}
+
+func TestConfiguration_LockName(t *testing.T) {
+	cfg := Configuration{K8SConfiguration: K8SConfiguration{LockName: "ledswitcher"}}
+	assert.Equal(t, "ledswitcher", cfg.LockName())
+	cfg.Group = "rack-a"
+	assert.Equal(t, "ledswitcher-rack-a", cfg.LockName())
+}
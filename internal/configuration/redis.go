@@ -0,0 +1,101 @@
+package configuration
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Client returns a redis client for the configuration. Depending on the configuration, this is a single node client,
+// a sentinel-backed failover client or a cluster client.
+func (r RedisConfiguration) Client() (redis.UniversalClient, error) {
+	opts, err := r.Options()
+	if err != nil {
+		return nil, err
+	}
+	return redis.NewUniversalClient(opts), nil
+}
+
+// Options returns the redis.UniversalOptions for the configuration.
+func (r RedisConfiguration) Options() (*redis.UniversalOptions, error) {
+	opts := redis.UniversalOptions{
+		Addrs:         splitAddrs(r.Addr),
+		Username:      r.Username,
+		Password:      r.Password,
+		DB:            r.DB,
+		MasterName:    r.MasterName,
+		IsClusterMode: r.Cluster,
+	}
+	if r.URL != "" {
+		urlOpts, err := redis.ParseURL(r.URL)
+		if err != nil {
+			return nil, fmt.Errorf("redis url: %w", err)
+		}
+		opts.Addrs = []string{urlOpts.Addr}
+		opts.Username = urlOpts.Username
+		opts.Password = urlOpts.Password
+		opts.DB = urlOpts.DB
+		opts.TLSConfig = urlOpts.TLSConfig
+	}
+	if r.MasterName != "" {
+		// with sentinel, the password is used for the sentinels too
+		opts.SentinelUsername = opts.Username
+		opts.SentinelPassword = opts.Password
+	}
+	tlsConfig, err := r.TLS.Config()
+	if err != nil {
+		return nil, fmt.Errorf("redis tls: %w", err)
+	}
+	if tlsConfig != nil {
+		opts.TLSConfig = tlsConfig
+	}
+	return &opts, nil
+}
+
+// Config returns the tls.Config for the configuration. If TLS is not enabled, Config returns nil.
+func (t TLSConfiguration) Config() (*tls.Config, error) {
+	if !t.Enabled {
+		return nil, nil
+	}
+	cfg := tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify, //nolint:gosec
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("ca: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca: no certificates found in %s", t.CAFile)
+		}
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, errors.New("client certificate requires both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return &cfg, nil
+}
+
+func splitAddrs(addr string) []string {
+	var addrs []string
+	for a := range strings.SplitSeq(addr, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,40 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Keys returns the signing key and the accepted keys of the configuration. If no key file is set, messages aren't
+// signed and Keys returns no keys.
+func (s SigningConfiguration) Keys() (key []byte, accepted [][]byte, err error) {
+	if s.KeyFile == "" {
+		if len(s.AcceptKeyFiles) > 0 {
+			return nil, nil, errors.New("accept-key-files requires a key-file")
+		}
+		return nil, nil, nil
+	}
+	if key, err = readKey(s.KeyFile); err != nil {
+		return nil, nil, err
+	}
+	accepted = make([][]byte, 0, len(s.AcceptKeyFiles))
+	for _, path := range s.AcceptKeyFiles {
+		k, err := readKey(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		accepted = append(accepted, k)
+	}
+	return key, accepted, nil
+}
+
+func readKey(path string) ([]byte, error) {
+	key, err := readSecret(path)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, fmt.Errorf("%s: key must not be empty", path)
+	}
+	return []byte(key), nil
+}
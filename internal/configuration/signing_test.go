@@ -0,0 +1,46 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigningConfiguration_Keys(t *testing.T) {
+	tmpDir := t.TempDir()
+	keyFile := filepath.Join(tmpDir, "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("secret\n"), 0600))
+	oldKeyFile := filepath.Join(tmpDir, "old-key")
+	require.NoError(t, os.WriteFile(oldKeyFile, []byte("old-secret\n"), 0600))
+	emptyFile := filepath.Join(tmpDir, "empty")
+	require.NoError(t, os.WriteFile(emptyFile, nil, 0600))
+	missingFile := filepath.Join(tmpDir, "missing")
+
+	tests := []struct {
+		name         string
+		cfg          SigningConfiguration
+		wantKey      []byte
+		wantAccepted [][]byte
+		wantErr      assert.ErrorAssertionFunc
+	}{
+		{name: "disabled", wantErr: assert.NoError},
+		{name: "key", cfg: SigningConfiguration{KeyFile: keyFile}, wantKey: []byte("secret"), wantAccepted: [][]byte{}, wantErr: assert.NoError},
+		{name: "rotation", cfg: SigningConfiguration{KeyFile: keyFile, AcceptKeyFiles: StringList{oldKeyFile}}, wantKey: []byte("secret"), wantAccepted: [][]byte{[]byte("old-secret")}, wantErr: assert.NoError},
+		{name: "missing key", cfg: SigningConfiguration{KeyFile: missingFile}, wantErr: assert.Error},
+		{name: "empty key", cfg: SigningConfiguration{KeyFile: emptyFile}, wantErr: assert.Error},
+		{name: "missing accepted key", cfg: SigningConfiguration{KeyFile: keyFile, AcceptKeyFiles: StringList{missingFile}}, wantErr: assert.Error},
+		{name: "empty accepted key", cfg: SigningConfiguration{KeyFile: keyFile, AcceptKeyFiles: StringList{emptyFile}}, wantErr: assert.Error},
+		{name: "accepted key without key", cfg: SigningConfiguration{AcceptKeyFiles: StringList{oldKeyFile}}, wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, accepted, err := tt.cfg.Keys()
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.wantKey, key)
+			assert.Equal(t, tt.wantAccepted, accepted)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// groupPattern matches valid group names. Group names are used in redis channel names and in the name of the k8s lock.
+var groupPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,38}[a-z0-9])?$`)
+
+// Validate checks the configuration for invalid values. It reports all problems found, rather than stopping at
+// the first one.
+//
+// Validate only checks the configuration itself. Settings that are interpreted by the schedule or the server, like the
+// pattern mode, the node order and the protocol, are checked when they are converted to the server's types.
+func (c Configuration) Validate() error {
+	var errs []error
+	if c.NodeName == "" {
+		errs = append(errs, errors.New("node-name: must not be empty"))
+	}
+	if c.Group != "" && !groupPattern.MatchString(c.Group) {
+		errs = append(errs, fmt.Errorf("group: must consist of lowercase letters, digits and '-', and start and end with a letter or digit (got %q)", c.Group))
+	}
+	if c.Addr == "" {
+		errs = append(errs, errors.New("addr: must not be empty"))
+	}
+	if c.EndpointConfiguration.LEDPath == "" {
+		errs = append(errs, errors.New("led-path: must not be empty"))
+	}
+	if mode := c.EndpointConfiguration.Fallback.Mode; mode != "" && mode != "none" && c.EndpointConfiguration.Fallback.Rotations <= 0 {
+		errs = append(errs, fmt.Errorf("fallback.rotations: must be positive (got %d)", c.EndpointConfiguration.Fallback.Rotations))
+	}
+	errs = append(errs, c.LeaderConfiguration.validate()...)
+	errs = append(errs, c.RegistryConfiguration.validate()...)
+	if c.LeaderConfiguration.Leader == "" {
+		errs = append(errs, c.K8SConfiguration.validate()...)
+	}
+	errs = append(errs, c.RedisConfiguration.validate()...)
+	if c.SigningConfiguration.KeyFile == "" && len(c.SigningConfiguration.AcceptKeyFiles) > 0 {
+		errs = append(errs, errors.New("signing: accept-key-files requires a key-file"))
+	}
+	return errors.Join(errs...)
+}
+
+func (l LeaderConfiguration) validate() []error {
+	var errs []error
+	if l.Rotation <= 0 {
+		errs = append(errs, fmt.Errorf("rotation: must be positive (got %s)", l.Rotation))
+	}
+	if l.LeadTime < 0 {
+		errs = append(errs, fmt.Errorf("lead-time: must not be negative (got %s)", l.LeadTime))
+	}
+	if l.WarmUp < 0 {
+		errs = append(errs, fmt.Errorf("warm-up: must not be negative (got %s)", l.WarmUp))
+	}
+	if l.Quorum < 0 {
+		errs = append(errs, fmt.Errorf("quorum: must not be negative (got %d)", l.Quorum))
+	}
+	if l.Checkpoint < 0 {
+		errs = append(errs, fmt.Errorf("checkpoint: must not be negative (got %s)", l.Checkpoint))
+	}
+	if l.Scheduler.MorseNode < 0 {
+		errs = append(errs, fmt.Errorf("morse.node: must not be negative (got %d)", l.Scheduler.MorseNode))
+	}
+	if l.Scheduler.Probability < 0 || l.Scheduler.Probability > 1 {
+		errs = append(errs, fmt.Errorf("sparkle.probability: must be between 0 and 1 (got %g)", l.Scheduler.Probability))
+	}
+	if l.Scheduler.K < 0 {
+		errs = append(errs, fmt.Errorf("exactly-k.count: must not be negative (got %d)", l.Scheduler.K))
+	}
+	if _, err := time.LoadLocation(l.Scheduler.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("clock.timezone: %w", err))
+	}
+	if l.Layout.Columns < 0 {
+		errs = append(errs, fmt.Errorf("layout.columns: must not be negative (got %d)", l.Layout.Columns))
+	}
+	return errs
+}
+
+func (r RegistryConfiguration) validate() []error {
+	var errs []error
+	if r.RegistrationInterval <= 0 {
+		errs = append(errs, fmt.Errorf("registry.interval: must be positive (got %s)", r.RegistrationInterval))
+	}
+	if r.NodeExpiration <= r.RegistrationInterval {
+		errs = append(errs, fmt.Errorf("registry.expiration: must be greater than registry.interval (got %s)", r.NodeExpiration))
+	}
+	if r.CleanupInterval <= 0 {
+		errs = append(errs, fmt.Errorf("registry.cleanup: must be positive (got %s)", r.CleanupInterval))
+	}
+	return errs
+}
+
+func (k K8SConfiguration) validate() []error {
+	var errs []error
+	if k.LockName == "" {
+		errs = append(errs, errors.New("lock-name: must not be empty when using k8s leader election"))
+	}
+	if k.Namespace == "" {
+		errs = append(errs, errors.New("lock-namespace: must not be empty when using k8s leader election"))
+	}
+	return errs
+}
+
+func (r RedisConfiguration) validate() []error {
+	var errs []error
+	if r.URL != "" {
+		if _, err := redis.ParseURL(r.URL); err != nil {
+			errs = append(errs, fmt.Errorf("redis.url: %w", err))
+		}
+	} else if len(splitAddrs(r.Addr)) == 0 {
+		errs = append(errs, errors.New("redis.addr: must not be empty"))
+	}
+	if r.DB < 0 {
+		errs = append(errs, fmt.Errorf("redis.db: must not be negative (got %d)", r.DB))
+	}
+	if r.DB != 0 && (r.Cluster || len(splitAddrs(r.Addr)) > 1 && r.MasterName == "") {
+		errs = append(errs, errors.New("redis.db: not supported for redis cluster"))
+	}
+	if _, err := r.TLS.Config(); err != nil {
+		errs = append(errs, fmt.Errorf("redis.tls: %w", err))
+	}
+	return errs
+}
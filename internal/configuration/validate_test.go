@@ -0,0 +1,196 @@
+package configuration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfiguration_Validate(t *testing.T) {
+	valid := Configuration{
+		Addr:     ":9090",
+		NodeName: "localhost",
+		EndpointConfiguration: EndpointConfiguration{
+			LEDPath:  "/sys/class/leds/led1",
+			Fallback: FallbackConfiguration{Mode: "none", Rotations: 5},
+		},
+		LeaderConfiguration: LeaderConfiguration{
+			Rotation:  time.Second,
+			Scheduler: SchedulerConfiguration{Mode: "linear"},
+		},
+		RegistryConfiguration: RegistryConfiguration{
+			RegistrationInterval: 10 * time.Second,
+			NodeExpiration:       time.Minute,
+			CleanupInterval:      10 * time.Minute,
+		},
+		K8SConfiguration:   K8SConfiguration{LockName: "ledswitcher", Namespace: "default"},
+		RedisConfiguration: RedisConfiguration{Addr: "localhost:6379"},
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Configuration)
+		want   string
+	}{
+		{
+			name:   "valid",
+			modify: func(*Configuration) {},
+		},
+		{
+			name: "all errors are reported",
+			modify: func(c *Configuration) {
+				c.NodeName = ""
+				c.Addr = ""
+				c.EndpointConfiguration.LEDPath = ""
+				c.LeaderConfiguration.Rotation = 0
+				c.RegistryConfiguration.RegistrationInterval = 0
+				c.K8SConfiguration = K8SConfiguration{}
+				c.RedisConfiguration.Addr = ""
+			},
+			want: `node-name: must not be empty
+addr: must not be empty
+led-path: must not be empty
+rotation: must be positive (got 0s)
+registry.interval: must be positive (got 0s)
+lock-name: must not be empty when using k8s leader election
+lock-namespace: must not be empty when using k8s leader election
+redis.addr: must not be empty`,
+		},
+		{
+			name: "invalid registry timing",
+			modify: func(c *Configuration) {
+				c.RegistryConfiguration.RegistrationInterval = time.Minute
+				c.RegistryConfiguration.CleanupInterval = 0
+			},
+			want: `registry.expiration: must be greater than registry.interval (got 1m0s)
+registry.cleanup: must be positive (got 0s)`,
+		},
+		{
+			name: "invalid morse node",
+			modify: func(c *Configuration) {
+				c.LeaderConfiguration.Scheduler.MorseNode = -1
+			},
+			want: "morse.node: must not be negative (got -1)",
+		},
+		{
+			name: "invalid random settings",
+			modify: func(c *Configuration) {
+				c.LeaderConfiguration.Scheduler.Probability = 1.5
+				c.LeaderConfiguration.Scheduler.K = -1
+			},
+			want: `sparkle.probability: must be between 0 and 1 (got 1.5)
+exactly-k.count: must not be negative (got -1)`,
+		},
+		{
+			name: "invalid time zone",
+			modify: func(c *Configuration) {
+				c.LeaderConfiguration.Scheduler.TimeZone = "Nowhere/Special"
+			},
+			want: "clock.timezone: unknown time zone Nowhere/Special",
+		},
+		{
+			name: "invalid lead time",
+			modify: func(c *Configuration) {
+				c.LeaderConfiguration.LeadTime = -time.Second
+			},
+			want: "lead-time: must not be negative (got -1s)",
+		},
+		{
+			name: "invalid layout",
+			modify: func(c *Configuration) {
+				c.LeaderConfiguration.Layout.Columns = -1
+			},
+			want: "layout.columns: must not be negative (got -1)",
+		},
+		{
+			name: "k8s settings not needed with static leader",
+			modify: func(c *Configuration) {
+				c.LeaderConfiguration.Leader = "localhost"
+				c.K8SConfiguration = K8SConfiguration{}
+			},
+		},
+		{
+			name: "redis url replaces addr",
+			modify: func(c *Configuration) {
+				c.RedisConfiguration.Addr = ""
+				c.RedisConfiguration.URL = "redis://localhost:6379/1"
+			},
+		},
+		{
+			name:   "invalid redis url",
+			modify: func(c *Configuration) { c.RedisConfiguration.URL = "http://localhost" },
+			want:   "redis.url: redis: invalid URL scheme: http",
+		},
+		{
+			name: "invalid redis db",
+			modify: func(c *Configuration) {
+				c.RedisConfiguration.DB = -1
+			},
+			want: "redis.db: must not be negative (got -1)",
+		},
+		{
+			name: "redis db with cluster",
+			modify: func(c *Configuration) {
+				c.RedisConfiguration.Addr = "redis-1:6379,redis-2:6379"
+				c.RedisConfiguration.DB = 1
+			},
+			want: "redis.db: not supported for redis cluster",
+		},
+		{
+			name: "invalid redis tls",
+			modify: func(c *Configuration) {
+				c.RedisConfiguration.TLS = TLSConfiguration{Enabled: true, CertFile: "cert.pem"}
+			},
+			want: "redis.tls: client certificate requires both a certificate and a key file",
+		},
+		{
+			name:   "negative warm-up",
+			modify: func(c *Configuration) { c.LeaderConfiguration.WarmUp = -time.Second },
+			want:   "warm-up: must not be negative (got -1s)",
+		},
+		{
+			name:   "negative quorum",
+			modify: func(c *Configuration) { c.LeaderConfiguration.Quorum = -1 },
+			want:   "quorum: must not be negative (got -1)",
+		},
+		{
+			name:   "negative checkpoint",
+			modify: func(c *Configuration) { c.LeaderConfiguration.Checkpoint = -time.Second },
+			want:   "checkpoint: must not be negative (got -1s)",
+		},
+		{
+			name:   "invalid fallback rotations",
+			modify: func(c *Configuration) { c.EndpointConfiguration.Fallback = FallbackConfiguration{Mode: "off"} },
+			want:   "fallback.rotations: must be positive (got 0)",
+		},
+		{
+			name:   "group",
+			modify: func(c *Configuration) { c.Group = "rack-a" },
+		},
+		{
+			name:   "invalid group",
+			modify: func(c *Configuration) { c.Group = "rack.a" },
+			want:   `group: must consist of lowercase letters, digits and '-', and start and end with a letter or digit (got "rack.a")`,
+		},
+		{
+			name: "accepted keys without signing key",
+			modify: func(c *Configuration) {
+				c.SigningConfiguration.AcceptKeyFiles = StringList{"old.key"}
+			},
+			want: "signing: accept-key-files requires a key-file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package configuration
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"flag"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// A Watcher reloads the configuration whenever the configuration file changes.
+//
+// Watcher polls the content of the file, rather than relying on file system events. This works reliably for
+// Kubernetes ConfigMap volumes, which update the file by swapping a symlink.
+type Watcher struct {
+	// OnChange is called with the new configuration. If OnChange returns an error, the configuration is rejected.
+	OnChange func(Configuration) error
+	Logger   *slog.Logger
+	// Args are the command-line arguments used to load the configuration. These are re-applied on every reload,
+	// so flags and environment variables keep their precedence over the configuration file.
+	Args []string
+	// Interval is the time between checks for changes. Defaults to 10 seconds.
+	Interval time.Duration
+}
+
+// Run checks the configuration file for changes until the context is canceled.
+func (w *Watcher) Run(ctx context.Context, path string) error {
+	w.Logger.Debug("configuration watcher started", "path", path)
+	defer w.Logger.Debug("configuration watcher stopped")
+
+	last, err := fileHash(path)
+	if err != nil {
+		w.Logger.Warn("failed to read configuration file", "err", err)
+	}
+
+	ticker := time.NewTicker(cmp.Or(w.Interval, 10*time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			current, err := fileHash(path)
+			if err != nil {
+				w.Logger.Warn("failed to read configuration file", "err", err)
+				continue
+			}
+			if bytes.Equal(current, last) {
+				continue
+			}
+			last = current
+			w.reload()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (w *Watcher) reload() {
+	cfg, err := Load(flag.NewFlagSet("reload", flag.ContinueOnError), w.Args)
+	if err != nil {
+		w.Logger.Error("invalid configuration. keeping current configuration", "err", err)
+		return
+	}
+	if err = w.OnChange(cfg); err != nil {
+		w.Logger.Error("configuration rejected. keeping current configuration", "err", err)
+		return
+	}
+	w.Logger.Info("configuration reloaded")
+}
+
+func fileHash(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(content)
+	return h[:], nil
+}
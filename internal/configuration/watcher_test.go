@@ -0,0 +1,59 @@
+package configuration
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher_Run(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("leader:\n  scheduler:\n    mode: linear\n"), 0600))
+
+	changes := make(chan Configuration, 10)
+	w := Watcher{
+		OnChange: func(cfg Configuration) error {
+			if cfg.LeaderConfiguration.Scheduler.Mode == "rejected" {
+				return errors.New("rejected")
+			}
+			changes <- cfg
+			return nil
+		},
+		Logger:   slog.New(slog.DiscardHandler),
+		Args:     []string{"-config=" + configFile},
+		Interval: 10 * time.Millisecond,
+	}
+	go func() { require.NoError(t, w.Run(t.Context(), configFile)) }()
+
+	// invalid configuration is not passed on
+	replaceFile(t, configFile, "leader:\n  rotation: invalid\n")
+	time.Sleep(100 * time.Millisecond)
+	// rejected configuration is not passed on
+	replaceFile(t, configFile, "leader:\n  scheduler:\n    mode: rejected\n")
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, changes)
+
+	// valid change
+	replaceFile(t, configFile, "leader:\n  scheduler:\n    mode: binary\n")
+	select {
+	case cfg := <-changes:
+		assert.Equal(t, "binary", cfg.LeaderConfiguration.Scheduler.Mode)
+	case <-time.After(time.Second):
+		t.Fatal("configuration change not detected")
+	}
+}
+
+// replaceFile replaces the file the way a ConfigMap volume does, so the Watcher never reads a partially written file.
+func replaceFile(t *testing.T, path string, content string) {
+	t.Helper()
+	newFile := path + ".new"
+	require.NoError(t, os.WriteFile(newFile, []byte(content), 0600))
+	require.NoError(t, os.Rename(newFile, path))
+}
@@ -1,5 +1,10 @@
 package schedule
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // AlternatingSchedule moves the LED from beginning to the end then moves from end to beginning again
 // (i.e., the Knight Rider pattern :-))
 type AlternatingSchedule struct {
@@ -7,14 +12,20 @@ type AlternatingSchedule struct {
 	direction int
 }
 
-var _ Schedule = &AlternatingSchedule{}
+var (
+	_ Schedule = &AlternatingSchedule{}
+	_ Resizer  = &AlternatingSchedule{}
+	_ Stateful = &AlternatingSchedule{}
+)
 
 // Next returns the next pattern
 func (s *AlternatingSchedule) Next(count int) []bool {
-	if count == 1 {
+	if count <= 1 {
 		return intToBits(1, count)
 	}
 
+	// a restored state may be beyond the last LED
+	s.index = min(s.index, count-1)
 	if s.index == 0 {
 		s.direction = 1
 	} else if s.index >= count-1 {
@@ -24,3 +35,32 @@ func (s *AlternatingSchedule) Next(count int) []bool {
 
 	return intToBits(1<<(count-s.index-1), count)
 }
+
+// Resize keeps the active LED at the same relative position, moving in the same direction
+func (s *AlternatingSchedule) Resize(oldCount, newCount int) {
+	s.index = rescale(s.index, oldCount, newCount)
+}
+
+type alternatingState struct {
+	Index     int `json:"index"`
+	Direction int `json:"direction"`
+}
+
+// MarshalState returns the position and direction of the active LED.
+func (s *AlternatingSchedule) MarshalState() ([]byte, error) {
+	return json.Marshal(alternatingState{Index: s.index, Direction: s.direction})
+}
+
+// UnmarshalState restores the position and direction of the active LED.
+func (s *AlternatingSchedule) UnmarshalState(data []byte) error {
+	var state alternatingState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	// the direction is only zero before the first pattern
+	if state.Index < 0 || (state.Direction != 1 && state.Direction != -1 && (state.Direction != 0 || state.Index != 0)) {
+		return fmt.Errorf("invalid state: index %d, direction %d", state.Index, state.Direction)
+	}
+	s.index, s.direction = state.Index, state.Direction
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/clambin/ledswitcher/internal/schedule"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAlternatingScheduler_Schedule(t *testing.T) {
@@ -43,3 +44,22 @@ func boolToString(input []bool) (output string) {
 	}
 	return
 }
+
+func TestAlternatingSchedule_Resize(t *testing.T) {
+	var s schedule.AlternatingSchedule
+	s.Next(4)
+	assert.Equal(t, "0010", boolToString(s.Next(4)))
+	s.Resize(4, 8)
+	assert.Equal(t, "00000100", boolToString(s.Next(8)))
+	s.Resize(8, 3)
+	assert.Equal(t, "001", boolToString(s.Next(3)))
+	assert.Equal(t, "010", boolToString(s.Next(3)))
+}
+
+func TestAlternatingSchedule_UnmarshalState(t *testing.T) {
+	var s schedule.AlternatingSchedule
+	// a state saved for more nodes continues from the last LED
+	require.NoError(t, s.UnmarshalState([]byte(`{"index":10,"direction":1}`)))
+	assert.Equal(t, "0010", boolToString(s.Next(4)))
+	assert.Equal(t, "0100", boolToString(s.Next(4)))
+}
@@ -1,19 +1,66 @@
 package schedule
 
-import "slices"
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"slices"
+)
 
-// BinarySchedule represents an increasing number as a set of bits
+// BinarySchedule represents an increasing number as a set of bits. It supports any number of nodes: with no nodes,
+// it returns an empty pattern.
 type BinarySchedule struct {
-	current int
+	current big.Int
 }
 
-var _ Schedule = &BinarySchedule{}
+var (
+	_ Schedule = &BinarySchedule{}
+	_ Resizer  = &BinarySchedule{}
+	_ Stateful = &BinarySchedule{}
+)
 
 // Next returns the next pattern
 func (s *BinarySchedule) Next(count int) []bool {
-	mask := (1 << (count)) - 1
-	s.current = (s.current + 1) & mask
-	return intToBits(s.current, count)
+	count = max(count, 0)
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(count))
+	s.current.Add(&s.current, big.NewInt(1))
+	s.current.Mod(&s.current, modulus)
+	return bigToBits(&s.current, count)
+}
+
+// Resize keeps the counter at the same relative position in its cycle, i.e. it keeps the most significant bits
+func (s *BinarySchedule) Resize(oldCount, newCount int) {
+	if oldCount <= 0 || newCount <= 0 {
+		s.current.SetInt64(0)
+		return
+	}
+	if newCount > oldCount {
+		s.current.Lsh(&s.current, uint(newCount-oldCount))
+	} else {
+		s.current.Rsh(&s.current, uint(oldCount-newCount))
+	}
+}
+
+type counterState struct {
+	Current *big.Int `json:"current"`
+}
+
+// MarshalState returns the current value of the counter.
+func (s *BinarySchedule) MarshalState() ([]byte, error) {
+	return json.Marshal(counterState{Current: &s.current})
+}
+
+// UnmarshalState restores the current value of the counter.
+func (s *BinarySchedule) UnmarshalState(data []byte) error {
+	var state counterState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Current == nil || state.Current.Sign() < 0 {
+		return errors.New("invalid counter")
+	}
+	s.current.Set(state.Current)
+	return nil
 }
 
 // ReverseBinarySchedule represents an increasing number as a set of bits, but in the reverse order as BinarySchedule
@@ -21,7 +68,10 @@ type ReverseBinarySchedule struct {
 	BinarySchedule
 }
 
-var _ Schedule = &ReverseBinarySchedule{}
+var (
+	_ Schedule = &ReverseBinarySchedule{}
+	_ Stateful = &ReverseBinarySchedule{}
+)
 
 // Next returns the next pattern
 func (s *ReverseBinarySchedule) Next(count int) []bool {
@@ -29,3 +79,12 @@ func (s *ReverseBinarySchedule) Next(count int) []bool {
 	slices.Reverse(bits)
 	return bits
 }
+
+// bigToBits returns the lowest count bits of val, most significant bit first.
+func bigToBits(val *big.Int, count int) []bool {
+	bits := make([]bool, count)
+	for i := range bits {
+		bits[i] = val.Bit(count-i-1) == 1
+	}
+	return bits
+}
@@ -2,9 +2,13 @@ package schedule_test
 
 import (
 	"fmt"
+	"math/big"
+	"slices"
 	"testing"
+	"testing/quick"
 
 	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -71,3 +75,76 @@ func TestReverseBinaryScheduler_Schedule(t *testing.T) {
 		require.Equal(t, tt.next, boolToString(next), fmt.Sprintf("testcase: %d", index+1))
 	}
 }
+
+func TestBinarySchedule_Properties(t *testing.T) {
+	// after n steps, the pattern is n modulo 2^width, for any width
+	counts := func(width uint16, steps uint8) bool {
+		count := int(width % 300)
+		var s schedule.BinarySchedule
+		var next []bool
+		for range int(steps) + 1 {
+			next = s.Next(count)
+		}
+		want := new(big.Int).Mod(big.NewInt(int64(steps)+1), new(big.Int).Lsh(big.NewInt(1), uint(count)))
+		return len(next) == count && bitsToBig(next).Cmp(want) == 0
+	}
+	assert.NoError(t, quick.Check(counts, nil))
+
+	// ReverseBinarySchedule mirrors BinarySchedule
+	mirrors := func(width uint16) bool {
+		count := int(width % 300)
+		var s schedule.BinarySchedule
+		var r schedule.ReverseBinarySchedule
+		for range 10 {
+			next := s.Next(count)
+			slices.Reverse(next)
+			if !slices.Equal(next, r.Next(count)) {
+				return false
+			}
+		}
+		return true
+	}
+	assert.NoError(t, quick.Check(mirrors, nil))
+}
+
+func TestBinarySchedule_Wrap(t *testing.T) {
+	var s schedule.BinarySchedule
+	for range 1<<10 - 2 {
+		s.Next(10)
+	}
+	assert.Equal(t, "1111111111", boolToString(s.Next(10)))
+	assert.Equal(t, "0000000000", boolToString(s.Next(10)))
+}
+
+func TestBinarySchedule_NoNodes(t *testing.T) {
+	var s schedule.BinarySchedule
+	assert.Empty(t, s.Next(0))
+	assert.Equal(t, "1", boolToString(s.Next(1)))
+}
+
+func bitsToBig(bits []bool) *big.Int {
+	val := new(big.Int)
+	for _, bit := range bits {
+		val.Lsh(val, 1)
+		if bit {
+			val.SetBit(val, 0, 1)
+		}
+	}
+	return val
+}
+
+func TestBinarySchedule_Resize(t *testing.T) {
+	var s schedule.BinarySchedule
+	for range 4 {
+		s.Next(3)
+	}
+	assert.Equal(t, "101", boolToString(s.Next(3)))
+	s.Resize(3, 4)
+	assert.Equal(t, "1011", boolToString(s.Next(4)))
+	s.Resize(4, 2)
+	assert.Equal(t, "11", boolToString(s.Next(2)))
+	s.Resize(2, 0)
+	assert.Empty(t, s.Next(0))
+	s.Resize(0, 2)
+	assert.Equal(t, "01", boolToString(s.Next(2)))
+}
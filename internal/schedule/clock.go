@@ -0,0 +1,65 @@
+package schedule
+
+import "time"
+
+// Clock returns the current time.
+type Clock func() time.Time
+
+// ClockFormat determines how BinaryClockSchedule represents the time.
+type ClockFormat int
+
+const (
+	// ClockBinary shows hours (5 bits), minutes (6 bits) and seconds (6 bits) in binary.
+	ClockBinary ClockFormat = iota
+	// ClockBCD shows each digit of hours, minutes and seconds as a binary-coded decimal:
+	// 2 bits for the tens of the hour, 3 bits for the tens of minutes and seconds, and 4 bits for each unit.
+	ClockBCD
+	// ClockUnix shows the Unix time, in seconds, in binary.
+	ClockUnix
+)
+
+// BinaryClockSchedule shows the current time in binary. If there are fewer LEDs than bits, the least significant bits are shown.
+type BinaryClockSchedule struct {
+	// Clock returns the current time. If nil, time.Now is used.
+	Clock Clock
+	// Location is the time zone used to show the time. If nil, the local time zone is used.
+	Location *time.Location
+	Format   ClockFormat
+}
+
+var _ Schedule = &BinaryClockSchedule{}
+
+// Next returns the next pattern
+func (s *BinaryClockSchedule) Next(count int) []bool {
+	return intToBits(s.value(), count)
+}
+
+func (s *BinaryClockSchedule) value() int {
+	now := s.now()
+	switch s.Format {
+	case ClockBCD:
+		var value int
+		for _, field := range []struct{ value, tensBits int }{
+			{now.Hour(), 2}, {now.Minute(), 3}, {now.Second(), 3},
+		} {
+			value = value<<field.tensBits | field.value/10
+			value = value<<4 | field.value%10
+		}
+		return value
+	case ClockUnix:
+		return int(now.Unix())
+	default:
+		return now.Hour()<<12 | now.Minute()<<6 | now.Second()
+	}
+}
+
+func (s *BinaryClockSchedule) now() time.Time {
+	now := time.Now
+	if s.Clock != nil {
+		now = s.Clock
+	}
+	if s.Location != nil {
+		return now().In(s.Location)
+	}
+	return now().Local()
+}
@@ -0,0 +1,44 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryClockSchedule(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 13, 47, 59, 0, time.UTC)
+	brussels, err := time.LoadLocation("Europe/Brussels")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		mode     string
+		location *time.Location
+		count    int
+		want     string
+	}{
+		// 13 = 01101, 47 = 101111, 59 = 111011
+		{name: "binary", mode: "clock", location: time.UTC, count: 17, want: "01101" + "101111" + "111011"},
+		{name: "binary, fewer leds", mode: "clock", location: time.UTC, count: 6, want: "111011"},
+		{name: "binary, time zone", mode: "clock", location: brussels, count: 17, want: "01110" + "101111" + "111011"},
+		// 1 3 : 4 7 : 5 9
+		{name: "bcd", mode: "clock-bcd", location: time.UTC, count: 20, want: "01" + "0011" + "100" + "0111" + "101" + "1001"},
+		{name: "unix", mode: "clock-unix", location: time.UTC, count: 8, want: "10001111"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := schedule.New(tt.mode, schedule.WithLocation(tt.location), schedule.WithClock(func() time.Time { return now }))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, boolToString(s.Next(tt.count)))
+		})
+	}
+}
+
+func TestBinaryClockSchedule_Defaults(t *testing.T) {
+	var s schedule.BinaryClockSchedule
+	assert.Len(t, s.Next(17), 17)
+}
@@ -0,0 +1,159 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// GrayCodeSchedule counts in Gray code: only one LED changes at each step
+type GrayCodeSchedule struct {
+	BinarySchedule
+}
+
+var (
+	_ Schedule = &GrayCodeSchedule{}
+	_ Resizer  = &GrayCodeSchedule{}
+	_ Stateful = &GrayCodeSchedule{}
+)
+
+// Next returns the next pattern
+func (s *GrayCodeSchedule) Next(count int) []bool {
+	s.BinarySchedule.Next(count)
+	gray := new(big.Int).Rsh(&s.current, 1)
+	gray.Xor(gray, &s.current)
+	return bigToBits(gray, max(count, 0))
+}
+
+// JohnsonSchedule is a Johnson counter: it switches on the LEDs one by one, from first to last, and then switches them
+// off again in the same order
+type JohnsonSchedule struct {
+	index int
+}
+
+var (
+	_ Schedule = &JohnsonSchedule{}
+	_ Resizer  = &JohnsonSchedule{}
+	_ Stateful = &JohnsonSchedule{}
+)
+
+// Next returns the next pattern
+func (s *JohnsonSchedule) Next(count int) []bool {
+	bits := make([]bool, max(count, 0))
+	if count <= 0 {
+		return bits
+	}
+	s.index = (s.index + 1) % (2 * count)
+	for i := range bits {
+		bits[i] = s.index <= count && i < s.index || s.index > count && i >= s.index-count
+	}
+	return bits
+}
+
+// Resize keeps the counter at the same relative position in its cycle
+func (s *JohnsonSchedule) Resize(oldCount, newCount int) {
+	s.index = rescale(s.index, 2*oldCount, 2*newCount)
+}
+
+// MarshalState returns the position in the cycle.
+func (s *JohnsonSchedule) MarshalState() ([]byte, error) {
+	return marshalIndex(s.index)
+}
+
+// UnmarshalState restores the position in the cycle.
+func (s *JohnsonSchedule) UnmarshalState(data []byte) (err error) {
+	s.index, err = unmarshalIndex(data)
+	return err
+}
+
+// FibonacciSchedule shows the Fibonacci numbers (1, 2, 3, 5, 8, ...) in binary. When the next number no longer
+// fits in the LEDs, it starts again from 1
+type FibonacciSchedule struct {
+	current, next *big.Int
+}
+
+var (
+	_ Schedule = &FibonacciSchedule{}
+	_ Stateful = &FibonacciSchedule{}
+)
+
+// Next returns the next pattern
+func (s *FibonacciSchedule) Next(count int) []bool {
+	count = max(count, 0)
+	if s.current == nil || s.current.BitLen() > count {
+		s.current, s.next = big.NewInt(1), big.NewInt(2)
+	}
+	bits := bigToBits(s.current, count)
+	s.current, s.next = s.next, new(big.Int).Add(s.current, s.next)
+	return bits
+}
+
+type fibonacciState struct {
+	Current *big.Int `json:"current,omitempty"`
+	Next    *big.Int `json:"next,omitempty"`
+}
+
+// MarshalState returns the next two Fibonacci numbers.
+func (s *FibonacciSchedule) MarshalState() ([]byte, error) {
+	return json.Marshal(fibonacciState{Current: s.current, Next: s.next})
+}
+
+// UnmarshalState restores the next two Fibonacci numbers.
+func (s *FibonacciSchedule) UnmarshalState(data []byte) error {
+	var state fibonacciState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if (state.Current == nil) != (state.Next == nil) ||
+		state.Current != nil && (state.Current.Sign() <= 0 || state.Next.Cmp(state.Current) <= 0) {
+		return errors.New("invalid fibonacci numbers")
+	}
+	s.current, s.next = state.Current, state.Next
+	return nil
+}
+
+// PrimeSchedule shows the prime numbers (2, 3, 5, 7, 11, ...) in binary. When the next prime no longer fits in the
+// LEDs, it starts again from 2
+type PrimeSchedule struct {
+	current big.Int
+}
+
+var (
+	_ Schedule = &PrimeSchedule{}
+	_ Stateful = &PrimeSchedule{}
+)
+
+// Next returns the next pattern
+func (s *PrimeSchedule) Next(count int) []bool {
+	count = max(count, 0)
+	s.current.Add(&s.current, big.NewInt(1))
+	for !s.current.ProbablyPrime(20) {
+		s.current.Add(&s.current, big.NewInt(1))
+	}
+	if s.current.BitLen() > count {
+		s.current.SetInt64(2)
+	}
+	if s.current.BitLen() > count {
+		// no prime fits in the LEDs
+		s.current.SetInt64(0)
+	}
+	return bigToBits(&s.current, count)
+}
+
+// MarshalState returns the last prime shown.
+func (s *PrimeSchedule) MarshalState() ([]byte, error) {
+	return json.Marshal(counterState{Current: &s.current})
+}
+
+// UnmarshalState restores the last prime shown.
+func (s *PrimeSchedule) UnmarshalState(data []byte) error {
+	var state counterState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Current == nil || state.Current.Sign() < 0 {
+		return errors.New("invalid prime")
+	}
+	s.current.Set(state.Current)
+	return nil
+}
@@ -0,0 +1,143 @@
+package schedule_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrayCodeSchedule(t *testing.T) {
+	tests := []struct {
+		count int
+		next  string
+	}{
+		{count: 3, next: "001"},
+		{count: 3, next: "011"},
+		{count: 3, next: "010"},
+		{count: 3, next: "110"},
+		{count: 3, next: "111"},
+		{count: 3, next: "101"},
+		{count: 3, next: "100"},
+		{count: 3, next: "000"},
+		{count: 2, next: "01"},
+		{count: 2, next: "11"},
+		{count: 2, next: "10"},
+		{count: 2, next: "00"},
+		{count: 0, next: ""},
+	}
+
+	var s schedule.GrayCodeSchedule
+
+	for index, tt := range tests {
+		next := s.Next(tt.count)
+		require.Equal(t, tt.next, boolToString(next), fmt.Sprintf("testcase: %d", index+1))
+	}
+}
+
+func TestGrayCodeSchedule_OneChangePerStep(t *testing.T) {
+	var s schedule.GrayCodeSchedule
+	previous := s.Next(100)
+	for range 1000 {
+		next := s.Next(100)
+		var changes int
+		for i := range next {
+			if next[i] != previous[i] {
+				changes++
+			}
+		}
+		require.Equal(t, 1, changes)
+		previous = next
+	}
+}
+
+func TestJohnsonSchedule(t *testing.T) {
+	tests := []struct {
+		count int
+		next  string
+	}{
+		{count: 3, next: "100"},
+		{count: 3, next: "110"},
+		{count: 3, next: "111"},
+		{count: 3, next: "011"},
+		{count: 3, next: "001"},
+		{count: 3, next: "000"},
+		{count: 3, next: "100"},
+		{count: 4, next: "1100"},
+		{count: 4, next: "1110"},
+		{count: 4, next: "1111"},
+		{count: 4, next: "0111"},
+		{count: 0, next: ""},
+	}
+
+	var s schedule.JohnsonSchedule
+
+	for index, tt := range tests {
+		next := s.Next(tt.count)
+		require.Equal(t, tt.next, boolToString(next), fmt.Sprintf("testcase: %d", index+1))
+	}
+}
+
+func TestJohnsonSchedule_Resize(t *testing.T) {
+	var s schedule.JohnsonSchedule
+	for range 3 {
+		s.Next(3)
+	}
+	assert.Equal(t, "011", boolToString(s.Next(3)))
+	s.Resize(3, 6)
+	assert.Equal(t, "000111", boolToString(s.Next(6)))
+}
+
+func TestFibonacciSchedule(t *testing.T) {
+	tests := []struct {
+		count int
+		next  string
+	}{
+		{count: 4, next: "0001"},
+		{count: 4, next: "0010"},
+		{count: 4, next: "0011"},
+		{count: 4, next: "0101"},
+		{count: 4, next: "1000"},
+		{count: 4, next: "1101"},
+		{count: 4, next: "0001"},
+		{count: 4, next: "0010"},
+		{count: 2, next: "11"},
+		{count: 2, next: "01"},
+		{count: 0, next: ""},
+	}
+
+	var s schedule.FibonacciSchedule
+
+	for index, tt := range tests {
+		next := s.Next(tt.count)
+		require.Equal(t, tt.next, boolToString(next), fmt.Sprintf("testcase: %d", index+1))
+	}
+}
+
+func TestPrimeSchedule(t *testing.T) {
+	tests := []struct {
+		count int
+		next  string
+	}{
+		{count: 4, next: "0010"},
+		{count: 4, next: "0011"},
+		{count: 4, next: "0101"},
+		{count: 4, next: "0111"},
+		{count: 4, next: "1011"},
+		{count: 4, next: "1101"},
+		{count: 4, next: "0010"},
+		{count: 2, next: "11"},
+		{count: 2, next: "10"},
+		{count: 1, next: "0"},
+		{count: 0, next: ""},
+	}
+
+	var s schedule.PrimeSchedule
+
+	for index, tt := range tests {
+		next := s.Next(tt.count)
+		require.Equal(t, tt.next, boolToString(next), fmt.Sprintf("testcase: %d", index+1))
+	}
+}
@@ -0,0 +1,59 @@
+package schedule
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// Descriptor describes a schedule and its parameters. Schedules created from the same Descriptor produce the same
+// patterns, provided Seed is set, so each node can recreate the schedule locally.
+type Descriptor struct {
+	Mode     string `json:"mode"`
+	Message  string `json:"message,omitempty"`
+	TimeZone string `json:"timeZone,omitempty"`
+	Taps     []int  `json:"taps,omitempty"`
+	// MorseNode is the position of the node showing the morse code, counting from 1 in pattern order. It is not a node
+	// name. See WithMorseNode.
+	MorseNode int `json:"morseNode,omitempty"`
+	// Seed is the seed for schedules that use random numbers. If zero, the patterns can't be reproduced.
+	Seed        uint64  `json:"seed,omitempty"`
+	Probability float64 `json:"probability,omitempty"`
+	K           int     `json:"k,omitempty"`
+}
+
+// New creates the Schedule described by the Descriptor.
+func (d Descriptor) New() (Schedule, error) {
+	options := []Option{
+		WithMessage(d.Message),
+		WithMorseNode(d.MorseNode),
+		WithProbability(d.Probability),
+		WithK(d.K),
+		WithTaps(d.Taps),
+	}
+	// LoadLocation("") returns UTC. Without a time zone, leave the location unset so the local time is shown.
+	if d.TimeZone != "" {
+		location, err := time.LoadLocation(d.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("time zone: %w", err)
+		}
+		options = append(options, WithLocation(location))
+	}
+	if d.Seed != 0 {
+		options = append(options, WithSource(rand.NewPCG(d.Seed, d.Seed)))
+	}
+	return New(d.Mode, options...)
+}
+
+// Equal reports whether two Descriptors describe the same schedule.
+func (d Descriptor) Equal(other Descriptor) bool {
+	return d.Mode == other.Mode &&
+		d.Message == other.Message &&
+		d.TimeZone == other.TimeZone &&
+		slices.Equal(d.Taps, other.Taps) &&
+		d.MorseNode == other.MorseNode &&
+		d.Seed == other.Seed &&
+		d.Probability == other.Probability &&
+		d.K == other.K
+}
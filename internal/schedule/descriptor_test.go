@@ -0,0 +1,55 @@
+package schedule_test
+
+import (
+	"testing"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescriptor_New(t *testing.T) {
+	tests := []struct {
+		name       string
+		descriptor schedule.Descriptor
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{name: "valid", descriptor: schedule.Descriptor{Mode: "lfsr", Taps: []int{3, 2}}, wantErr: assert.NoError},
+		{name: "invalid mode", descriptor: schedule.Descriptor{Mode: "invalid"}, wantErr: assert.Error},
+		{name: "invalid time zone", descriptor: schedule.Descriptor{Mode: "clock", TimeZone: "Nowhere/Special"}, wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.descriptor.New()
+			tt.wantErr(t, err)
+		})
+	}
+}
+
+func TestDescriptor_New_TimeZone(t *testing.T) {
+	s, err := schedule.Descriptor{Mode: "clock"}.New()
+	require.NoError(t, err)
+	assert.Nil(t, s.(*schedule.BinaryClockSchedule).Location)
+
+	s, err = schedule.Descriptor{Mode: "clock", TimeZone: "Europe/Brussels"}.New()
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Brussels", s.(*schedule.BinaryClockSchedule).Location.String())
+}
+
+func TestDescriptor_Reproducible(t *testing.T) {
+	d := schedule.Descriptor{Mode: "sparkle", Seed: 42, Probability: 0.5}
+	s1, err := d.New()
+	require.NoError(t, err)
+	s2, err := d.New()
+	require.NoError(t, err)
+	for range 20 {
+		assert.Equal(t, s1.Next(10), s2.Next(10))
+	}
+}
+
+func TestDescriptor_Equal(t *testing.T) {
+	d := schedule.Descriptor{Mode: "lfsr", Taps: []int{3, 2}}
+	assert.True(t, d.Equal(schedule.Descriptor{Mode: "lfsr", Taps: []int{3, 2}}))
+	assert.False(t, d.Equal(schedule.Descriptor{Mode: "lfsr", Taps: []int{3}}))
+	assert.False(t, d.Equal(schedule.Descriptor{Mode: "lfsr", Taps: []int{3, 2}, Seed: 1}))
+}
@@ -0,0 +1,48 @@
+package schedule
+
+// DiagonalWaveSchedule switches on one diagonal of LEDs at a time, moving from the top-left corner to the bottom-right corner.
+type DiagonalWaveSchedule struct {
+	index int
+}
+
+var (
+	_ Schedule2D = &DiagonalWaveSchedule{}
+	_ Stateful   = &DiagonalWaveSchedule{}
+)
+
+// Next returns the next pattern
+func (s *DiagonalWaveSchedule) Next(count int) []bool {
+	return singleRow(s, count)
+}
+
+// Next2D returns the next pattern
+func (s *DiagonalWaveSchedule) Next2D(grid Grid) [][]bool {
+	cells := newCells(grid)
+	if grid.Cells() == 0 {
+		return cells
+	}
+	diagonals := grid.Rows + grid.Columns - 1
+	if !grid.Filled(grid.Rows-1, grid.Columns-1) {
+		// the last diagonal only holds the bottom-right cell
+		diagonals--
+	}
+	diagonal := s.index % diagonals
+	for row := range cells {
+		if column := diagonal - row; column >= 0 && column < grid.Columns {
+			cells[row][column] = true
+		}
+	}
+	s.index = diagonal + 1
+	return cells
+}
+
+// MarshalState returns the next diagonal.
+func (s *DiagonalWaveSchedule) MarshalState() ([]byte, error) {
+	return marshalIndex(s.index)
+}
+
+// UnmarshalState restores the next diagonal.
+func (s *DiagonalWaveSchedule) UnmarshalState(data []byte) (err error) {
+	s.index, err = unmarshalIndex(data)
+	return err
+}
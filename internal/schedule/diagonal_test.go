@@ -0,0 +1,45 @@
+package schedule_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagonalWaveSchedule(t *testing.T) {
+	s := schedule.DiagonalWaveSchedule{}
+	grid := schedule.Grid{Rows: 3, Columns: 4}
+
+	want := []string{
+		"1000/0000/0000",
+		"0100/1000/0000",
+		"0010/0100/1000",
+		"0001/0010/0100",
+		"0000/0001/0010",
+		"0000/0000/0001",
+		"1000/0000/0000",
+	}
+
+	for index, next := range want {
+		assert.Equal(t, next, gridToString(s.Next2D(grid)), fmt.Sprintf("testcase: %d", index+1))
+	}
+}
+
+func TestDiagonalWaveSchedule_PartialGrid(t *testing.T) {
+	s := schedule.DiagonalWaveSchedule{}
+	grid := schedule.Grid{Rows: 2, Columns: 3, LEDs: 5}
+
+	// the bottom-right cell has no LED, so the last diagonal is skipped
+	want := []string{
+		"100/000",
+		"010/100",
+		"001/010",
+		"100/000",
+	}
+
+	for index, next := range want {
+		assert.Equal(t, next, gridToString(s.Next2D(grid)), fmt.Sprintf("testcase: %d", index+1))
+	}
+}
@@ -0,0 +1,75 @@
+package schedule
+
+import "strings"
+
+// fontHeight is the height of the glyphs in the built-in font.
+const fontHeight = 5
+
+// font is a 5-row bitmap font. Each glyph is a list of rows, with '#' marking a lit pixel.
+var font = map[rune][fontHeight]string{
+	'A': {".#.", "#.#", "###", "#.#", "#.#"},
+	'B': {"##.", "#.#", "##.", "#.#", "##."},
+	'C': {".##", "#..", "#..", "#..", ".##"},
+	'D': {"##.", "#.#", "#.#", "#.#", "##."},
+	'E': {"###", "#..", "##.", "#..", "###"},
+	'F': {"###", "#..", "##.", "#..", "#.."},
+	'G': {".##", "#..", "#.#", "#.#", ".##"},
+	'H': {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I': {"###", ".#.", ".#.", ".#.", "###"},
+	'J': {"..#", "..#", "..#", "#.#", ".#."},
+	'K': {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L': {"#..", "#..", "#..", "#..", "###"},
+	'M': {"#...#", "##.##", "#.#.#", "#...#", "#...#"},
+	'N': {"#..#", "##.#", "#.##", "#..#", "#..#"},
+	'O': {".#.", "#.#", "#.#", "#.#", ".#."},
+	'P': {"##.", "#.#", "##.", "#..", "#.."},
+	'Q': {".#.", "#.#", "#.#", "##.", ".##"},
+	'R': {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S': {".##", "#..", ".#.", "..#", "##."},
+	'T': {"###", ".#.", ".#.", ".#.", ".#."},
+	'U': {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V': {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W': {"#...#", "#...#", "#.#.#", "##.##", "#...#"},
+	'X': {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y': {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z': {"###", "..#", ".#.", "#..", "###"},
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"##.", "..#", ".#.", "#..", "###"},
+	'3': {"##.", "..#", ".#.", "..#", "##."},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "##.", "..#", "##."},
+	'6': {".##", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", ".#.", ".#.", ".#."},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "##."},
+	' ': {"..", "..", "..", "..", ".."},
+	'.': {".", ".", ".", ".", "#"},
+	',': {"..", "..", "..", ".#", "#."},
+	'!': {"#", "#", "#", ".", "#"},
+	'?': {"##.", "..#", ".#.", "...", ".#."},
+	'-': {"...", "...", "###", "...", "..."},
+	':': {".", "#", ".", "#", "."},
+	'/': {"..#", "..#", ".#.", "#..", "#.."},
+}
+
+// render renders the text in the built-in font, with one blank column between glyphs. The result is indexed
+// by row and column. Characters that are not in the font are ignored.
+func render(text string) [fontHeight][]bool {
+	var bitmap [fontHeight][]bool
+	for _, r := range strings.ToUpper(text) {
+		glyph, ok := font[r]
+		if !ok {
+			continue
+		}
+		for row := range fontHeight {
+			if len(bitmap[row]) > 0 {
+				bitmap[row] = append(bitmap[row], false)
+			}
+			for _, pixel := range glyph[row] {
+				bitmap[row] = append(bitmap[row], pixel == '#')
+			}
+		}
+	}
+	return bitmap
+}
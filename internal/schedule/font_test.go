@@ -0,0 +1,21 @@
+package schedule
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFont(t *testing.T) {
+	for r, glyph := range font {
+		for row := range fontHeight {
+			assert.Len(t, glyph[row], len(glyph[0]), "glyph %q, row %d", r, row)
+		}
+	}
+}
+
+func Test_render(t *testing.T) {
+	bitmap := render("i~i")
+	assert.Equal(t, []bool{true, true, true, false, true, true, true}, bitmap[0])
+	assert.Equal(t, []bool{false, true, false, false, false, true, false}, bitmap[1])
+}
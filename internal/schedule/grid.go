@@ -0,0 +1,46 @@
+package schedule
+
+// Grid is the size of a two-dimensional layout of LEDs. The LEDs fill the grid row by row, so only the last row
+// can have cells without a LED.
+type Grid struct {
+	Rows    int
+	Columns int
+	// LEDs is the number of LEDs in the grid. If zero, every cell holds a LED.
+	LEDs int
+}
+
+// Cells returns the number of cells in the grid.
+func (g Grid) Cells() int {
+	return g.Rows * g.Columns
+}
+
+// Filled reports whether the cell at the specified row and column holds a LED.
+func (g Grid) Filled(row, column int) bool {
+	return g.LEDs <= 0 || row*g.Columns+column < g.LEDs
+}
+
+// Schedule2D determines the next state of a two-dimensional grid of LEDs.
+//
+// Next2D returns the state of each LED, indexed by row and column. A Schedule2D also implements Schedule,
+// treating the LEDs as a single row.
+type Schedule2D interface {
+	Schedule
+	Next2D(grid Grid) [][]bool
+}
+
+// newCells returns a grid of LEDs that are all switched off.
+func newCells(grid Grid) [][]bool {
+	cells := make([][]bool, grid.Rows)
+	for row := range cells {
+		cells[row] = make([]bool, grid.Columns)
+	}
+	return cells
+}
+
+// singleRow runs a Schedule2D for a single row of count LEDs.
+func singleRow(s Schedule2D, count int) []bool {
+	if count <= 0 {
+		return []bool{}
+	}
+	return s.Next2D(Grid{Rows: 1, Columns: count})[0]
+}
@@ -0,0 +1,130 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maximalTaps are the taps of a maximal-length LFSR for each register width, i.e. LFSRs that go through all 2^n-1
+// non-zero states before repeating.
+var maximalTaps = map[int][]int{
+	1:  {1},
+	2:  {2, 1},
+	3:  {3, 2},
+	4:  {4, 3},
+	5:  {5, 3},
+	6:  {6, 5},
+	7:  {7, 6},
+	8:  {8, 6, 5, 4},
+	9:  {9, 5},
+	10: {10, 7},
+	11: {11, 9},
+	12: {12, 6, 4, 1},
+	13: {13, 4, 3, 1},
+	14: {14, 5, 3, 1},
+	15: {15, 14},
+	16: {16, 15, 13, 4},
+	17: {17, 14},
+	18: {18, 11},
+	19: {19, 6, 2, 1},
+	20: {20, 17},
+	21: {21, 19},
+	22: {22, 21},
+	23: {23, 18},
+	24: {24, 23, 22, 17},
+}
+
+// LFSRSchedule shows the state of a linear-feedback shift register (Fibonacci form), one bit per LED. This gives a
+// pseudo-random pattern that repeats after at most 2^n-1 steps.
+type LFSRSchedule struct {
+	state []bool
+	// Taps are the (1-based) positions of the register bits that are XOR'ed to give the next input bit, i.e. the
+	// exponents of the feedback polynomial. Taps beyond the number of LEDs are ignored. If empty, the taps of
+	// a maximal-length LFSR are used, where known.
+	Taps []int
+}
+
+var (
+	_ Schedule = &LFSRSchedule{}
+	_ Stateful = &LFSRSchedule{}
+)
+
+// Next returns the next pattern
+func (s *LFSRSchedule) Next(count int) []bool {
+	count = max(count, 0)
+	if len(s.state) != count || !slices.Contains(s.state, true) {
+		// new register, or stuck at zero: (re)seed the register
+		s.state = make([]bool, count)
+		if count > 0 {
+			s.state[count-1] = true
+		}
+	}
+	if count == 0 {
+		return s.state
+	}
+	var feedback bool
+	for _, tap := range s.taps(count) {
+		if tap >= 1 && tap <= count {
+			feedback = feedback != s.state[tap-1]
+		}
+	}
+	copy(s.state[1:], s.state[:count-1])
+	s.state[0] = feedback
+	return slices.Clone(s.state)
+}
+
+type lfsrState struct {
+	Register []bool `json:"register"`
+}
+
+// MarshalState returns the state of the register.
+func (s *LFSRSchedule) MarshalState() ([]byte, error) {
+	return json.Marshal(lfsrState{Register: s.state})
+}
+
+// UnmarshalState restores the state of the register.
+func (s *LFSRSchedule) UnmarshalState(data []byte) error {
+	var state lfsrState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	// an empty register is the state before the first pattern. Otherwise, the register can't be stuck at zero.
+	if len(state.Register) > 0 && !slices.Contains(state.Register, true) {
+		return errors.New("invalid state: register is zero")
+	}
+	s.state = state.Register
+	return nil
+}
+
+func (s *LFSRSchedule) taps(count int) []int {
+	if len(s.Taps) > 0 {
+		return s.Taps
+	}
+	if taps, ok := maximalTaps[count]; ok {
+		return taps
+	}
+	return []int{count, count - 1}
+}
+
+// ParseTaps parses a comma-separated list of LFSR taps (e.g. "16,15,13,4").
+func ParseTaps(value string) ([]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var taps []int
+	for field := range strings.SplitSeq(value, ",") {
+		tap, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tap %q: %w", field, err)
+		}
+		if tap < 1 {
+			return nil, errors.New("taps must be positive")
+		}
+		taps = append(taps, tap)
+	}
+	return taps, nil
+}
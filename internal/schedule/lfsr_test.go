@@ -0,0 +1,81 @@
+package schedule_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLFSRSchedule(t *testing.T) {
+	tests := []struct {
+		count int
+		next  string
+	}{
+		{count: 3, next: "100"},
+		{count: 3, next: "010"},
+		{count: 3, next: "101"},
+		{count: 3, next: "110"},
+		{count: 3, next: "111"},
+		{count: 3, next: "011"},
+		{count: 3, next: "001"},
+		{count: 3, next: "100"},
+		{count: 2, next: "10"},
+		{count: 2, next: "11"},
+		{count: 2, next: "01"},
+		{count: 0, next: ""},
+	}
+
+	var s schedule.LFSRSchedule
+
+	for index, tt := range tests {
+		next := s.Next(tt.count)
+		require.Equal(t, tt.next, boolToString(next), fmt.Sprintf("testcase: %d", index+1))
+	}
+}
+
+func TestLFSRSchedule_Taps(t *testing.T) {
+	// x^4 + x + 1
+	s, err := schedule.New("lfsr", schedule.WithTaps([]int{4, 1}))
+	require.NoError(t, err)
+	var got []string
+	for range 6 {
+		got = append(got, boolToString(s.Next(4)))
+	}
+	assert.Equal(t, []string{"1000", "1100", "1110", "1111", "0111", "1011"}, got)
+}
+
+func TestLFSRSchedule_MaximalLength(t *testing.T) {
+	for count := 1; count <= 16; count++ {
+		t.Run(fmt.Sprintf("%d", count), func(t *testing.T) {
+			var s schedule.LFSRSchedule
+			seen := make(map[string]struct{})
+			for range 1<<count - 1 {
+				seen[boolToString(s.Next(count))] = struct{}{}
+			}
+			assert.Len(t, seen, max(1<<count-1, 1))
+		})
+	}
+}
+
+func TestParseTaps(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []int
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{value: "", wantErr: assert.NoError},
+		{value: "16,15, 13,4", want: []int{16, 15, 13, 4}, wantErr: assert.NoError},
+		{value: "16,x", wantErr: assert.Error},
+		{value: "16,0", wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			taps, err := schedule.ParseTaps(tt.value)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.want, taps)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// LifeSchedule plays Conway's Game of Life on the grid. Cells outside the grid, or without a LED, are considered dead.
+// When all cells die, or the game gets stuck in a still life or a blinker, the grid is reseeded at random.
+type LifeSchedule struct {
+	randomSource
+	current  [][]bool
+	previous [][]bool
+	grid     Grid
+}
+
+var (
+	_ Schedule2D = &LifeSchedule{}
+	_ Stateful   = &LifeSchedule{}
+)
+
+// Next returns the next pattern
+func (s *LifeSchedule) Next(count int) []bool {
+	return singleRow(s, count)
+}
+
+// Next2D returns the next pattern
+func (s *LifeSchedule) Next2D(grid Grid) [][]bool {
+	if grid.Cells() == 0 {
+		return newCells(grid)
+	}
+	if grid != s.grid || s.current == nil {
+		s.grid = grid
+		s.seed()
+		return copyCells(s.current)
+	}
+	next := s.generation()
+	if !s.alive(next) || equalCells(next, s.current) || equalCells(next, s.previous) {
+		s.seed()
+		return copyCells(s.current)
+	}
+	s.previous, s.current = s.current, next
+	return copyCells(s.current)
+}
+
+type lifeState struct {
+	Current  [][]bool `json:"current"`
+	Previous [][]bool `json:"previous,omitempty"`
+	Grid     Grid     `json:"grid"`
+}
+
+// MarshalState returns the last two generations.
+func (s *LifeSchedule) MarshalState() ([]byte, error) {
+	return json.Marshal(lifeState{Current: s.current, Previous: s.previous, Grid: s.grid})
+}
+
+// UnmarshalState restores the last two generations.
+func (s *LifeSchedule) UnmarshalState(data []byte) error {
+	var state lifeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Current != nil && !fits(state.Current, state.Grid) || state.Previous != nil && !fits(state.Previous, state.Grid) {
+		return errors.New("generation doesn't match grid")
+	}
+	s.current, s.previous, s.grid = state.Current, state.Previous, state.Grid
+	return nil
+}
+
+func (s *LifeSchedule) seed() {
+	s.previous = nil
+	s.current = newCells(s.grid)
+	for !s.alive(s.current) {
+		for row := range s.current {
+			for column := range s.current[row] {
+				s.current[row][column] = s.grid.Filled(row, column) && s.random().IntN(3) == 0
+			}
+		}
+	}
+}
+
+// generation returns the next generation of the current grid.
+func (s *LifeSchedule) generation() [][]bool {
+	next := newCells(s.grid)
+	for row := range next {
+		for column := range next[row] {
+			neighbours := s.neighbours(row, column)
+			next[row][column] = s.grid.Filled(row, column) && (neighbours == 3 || (neighbours == 2 && s.current[row][column]))
+		}
+	}
+	return next
+}
+
+func (s *LifeSchedule) neighbours(row, column int) int {
+	var count int
+	for r := max(row-1, 0); r <= min(row+1, s.grid.Rows-1); r++ {
+		for c := max(column-1, 0); c <= min(column+1, s.grid.Columns-1); c++ {
+			if (r != row || c != column) && s.current[r][c] && s.grid.Filled(r, c) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// alive returns true if any cell with a LED is alive.
+func (s *LifeSchedule) alive(cells [][]bool) bool {
+	for row := range cells {
+		for column, cell := range cells[row] {
+			if cell && s.grid.Filled(row, column) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fits returns true if the cells have the size of the grid.
+func fits(cells [][]bool, grid Grid) bool {
+	if len(cells) != grid.Rows {
+		return false
+	}
+	for _, row := range cells {
+		if len(row) != grid.Columns {
+			return false
+		}
+	}
+	return true
+}
+
+func equalCells(a, b [][]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for row := range a {
+		if len(a[row]) != len(b[row]) {
+			return false
+		}
+		for column := range a[row] {
+			if a[row][column] != b[row][column] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func copyCells(cells [][]bool) [][]bool {
+	c := make([][]bool, len(cells))
+	for row := range cells {
+		c[row] = append([]bool(nil), cells[row]...)
+	}
+	return c
+}
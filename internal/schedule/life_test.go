@@ -0,0 +1,99 @@
+package schedule
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifeSchedule(t *testing.T) {
+	var s LifeSchedule
+	grid := Grid{Rows: 3, Columns: 4}
+	for range 100 {
+		next := s.Next2D(grid)
+		require.Len(t, next, grid.Rows)
+		for _, row := range next {
+			require.Len(t, row, grid.Columns)
+		}
+		assert.True(t, s.alive(next))
+	}
+	assert.Len(t, s.Next(5), 5)
+}
+
+func TestLifeSchedule_generation(t *testing.T) {
+	// a glider moves diagonally
+	s := LifeSchedule{
+		grid: Grid{Rows: 5, Columns: 5},
+		current: [][]bool{
+			{false, true, false, false, false},
+			{false, false, true, false, false},
+			{true, true, true, false, false},
+			{false, false, false, false, false},
+			{false, false, false, false, false},
+		},
+	}
+	want := [][]bool{
+		{false, false, false, false, false},
+		{true, false, true, false, false},
+		{false, true, true, false, false},
+		{false, true, false, false, false},
+		{false, false, false, false, false},
+	}
+	assert.Equal(t, want, s.Next2D(s.grid))
+}
+
+func TestLifeSchedule_reseed(t *testing.T) {
+	// a block is a still life: the grid is reseeded
+	block := [][]bool{
+		{true, true, false},
+		{true, true, false},
+		{false, false, false},
+	}
+	s := LifeSchedule{grid: Grid{Rows: 3, Columns: 3}, current: copyCells(block)}
+	next := s.Next2D(s.grid)
+	assert.True(t, s.alive(next))
+	assert.Nil(t, s.previous)
+}
+
+func TestLifeSchedule_PartialGrid(t *testing.T) {
+	grid := Grid{Rows: 2, Columns: 3, LEDs: 4}
+	var s LifeSchedule
+	for range 100 {
+		next := s.Next2D(grid)
+		// cells without a LED are never alive
+		assert.False(t, next[1][1] || next[1][2])
+		assert.True(t, next[0][0] || next[0][1] || next[0][2] || next[1][0])
+	}
+
+	// live cells without a LED don't keep the game going: the grid is reseeded
+	s = LifeSchedule{grid: grid, current: [][]bool{
+		{false, false, false},
+		{false, true, true},
+	}}
+	next := s.Next2D(grid)
+	assert.True(t, s.alive(next))
+	assert.Nil(t, s.previous)
+}
+
+func TestLifeSchedule_State(t *testing.T) {
+	grid := Grid{Rows: 5, Columns: 5}
+	glider := LifeSchedule{
+		grid: grid,
+		current: [][]bool{
+			{false, true, false, false, false},
+			{false, false, true, false, false},
+			{true, true, true, false, false},
+			{false, false, false, false, false},
+			{false, false, false, false, false},
+		},
+	}
+	glider.Next2D(grid)
+	state, err := glider.MarshalState()
+	require.NoError(t, err)
+
+	var restored LifeSchedule
+	require.NoError(t, restored.UnmarshalState(state))
+	assert.Equal(t, glider.Next2D(grid), restored.Next2D(grid))
+	assert.Equal(t, glider.previous, restored.previous)
+}
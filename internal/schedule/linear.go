@@ -5,10 +5,38 @@ type LinearSchedule struct {
 	index int
 }
 
-var _ Schedule = &LinearSchedule{}
+var (
+	_ Schedule = &LinearSchedule{}
+	_ Resizer  = &LinearSchedule{}
+	_ Stateful = &LinearSchedule{}
+)
 
 // Next returns the next pattern
 func (ls *LinearSchedule) Next(count int) []bool {
 	ls.index = (ls.index + 1) % count
 	return intToBits(1<<(count-ls.index-1), count)
 }
+
+// Resize keeps the active LED at the same relative position
+func (ls *LinearSchedule) Resize(oldCount, newCount int) {
+	ls.index = rescale(ls.index, oldCount, newCount)
+}
+
+// MarshalState returns the position in the pattern.
+func (ls *LinearSchedule) MarshalState() ([]byte, error) {
+	return marshalIndex(ls.index)
+}
+
+// UnmarshalState restores the position in the pattern.
+func (ls *LinearSchedule) UnmarshalState(data []byte) (err error) {
+	ls.index, err = unmarshalIndex(data)
+	return err
+}
+
+// rescale maps a position among oldCount nodes to the same relative position among newCount nodes.
+func rescale(index, oldCount, newCount int) int {
+	if oldCount <= 0 || newCount <= 0 {
+		return 0
+	}
+	return min(index*newCount/oldCount, newCount-1)
+}
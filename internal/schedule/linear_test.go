@@ -30,3 +30,13 @@ func TestLinearScheduler_Schedule(t *testing.T) {
 		assert.Equal(t, testCase.next, boolToString(next), fmt.Sprintf("testcase: %d", index+1))
 	}
 }
+
+func TestLinearSchedule_Resize(t *testing.T) {
+	var s schedule.LinearSchedule
+	s.Next(4)
+	assert.Equal(t, "0010", boolToString(s.Next(4)))
+	s.Resize(4, 8)
+	assert.Equal(t, "00000100", boolToString(s.Next(8)))
+	s.Resize(8, 2)
+	assert.Equal(t, "10", boolToString(s.Next(2)))
+}
@@ -0,0 +1,74 @@
+package schedule
+
+// MarqueeSchedule scrolls a message from right to left across the grid, using a built-in 5-row font.
+// On grids with fewer than five rows, the font is scaled down by skipping rows. On taller grids, the text is centered.
+// After the message has scrolled off the grid, it starts again.
+type MarqueeSchedule struct {
+	bitmap  [fontHeight][]bool
+	message string
+	offset  int
+}
+
+var _ Schedule2D = &MarqueeSchedule{}
+var _ MessageSchedule = &MarqueeSchedule{}
+var _ Stateful = &MarqueeSchedule{}
+
+// SetMessage sets the message to display. The message restarts from the beginning.
+func (s *MarqueeSchedule) SetMessage(message string) {
+	s.message = message
+	s.bitmap = render(message)
+	s.offset = 0
+}
+
+// Next returns the next pattern
+func (s *MarqueeSchedule) Next(count int) []bool {
+	return singleRow(s, count)
+}
+
+// Next2D returns the next pattern
+func (s *MarqueeSchedule) Next2D(grid Grid) [][]bool {
+	cells := newCells(grid)
+	width := len(s.bitmap[0])
+	if grid.Cells() == 0 || width == 0 {
+		return cells
+	}
+	// the text scrolls in from the right and scrolls out completely, leaving one blank frame, before it restarts
+	s.offset %= width + grid.Columns
+	for row := range grid.Rows {
+		fontRow, ok := s.fontRow(row, grid.Rows)
+		if !ok {
+			continue
+		}
+		for column := range grid.Columns {
+			if x := s.offset + column - grid.Columns + 1; x >= 0 && x < width {
+				cells[row][column] = s.bitmap[fontRow][x]
+			}
+		}
+	}
+	s.offset++
+	return cells
+}
+
+// MarshalState returns the scroll position of the message.
+func (s *MarqueeSchedule) MarshalState() ([]byte, error) {
+	return marshalIndex(s.offset)
+}
+
+// UnmarshalState restores the scroll position of the message. Call SetMessage first: it restarts the message.
+func (s *MarqueeSchedule) UnmarshalState(data []byte) (err error) {
+	s.offset, err = unmarshalIndex(data)
+	return err
+}
+
+// fontRow returns the row of the font to show on a grid row.
+func (s *MarqueeSchedule) fontRow(row, rows int) (int, bool) {
+	if rows < fontHeight {
+		// scale down: spread the grid rows evenly over the font
+		if rows == 1 {
+			return fontHeight / 2, true
+		}
+		return row * (fontHeight - 1) / (rows - 1), true
+	}
+	fontRow := row - (rows-fontHeight)/2
+	return fontRow, fontRow >= 0 && fontRow < fontHeight
+}
@@ -0,0 +1,63 @@
+package schedule_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarqueeSchedule(t *testing.T) {
+	var s schedule.MarqueeSchedule
+	s.SetMessage("hi")
+	grid := schedule.Grid{Rows: 5, Columns: 3}
+
+	// H is 3 columns wide, followed by a blank column and the 3 columns of I
+	want := []string{
+		"001/001/001/001/001",
+		"010/010/011/010/010",
+		"101/101/111/101/101",
+		"010/010/110/010/010",
+		"101/100/100/100/101",
+		"011/001/001/001/011",
+		"111/010/010/010/111",
+		"110/100/100/100/110",
+		"100/000/000/000/100",
+		"000/000/000/000/000",
+		"001/001/001/001/001",
+	}
+	for index, next := range want {
+		assert.Equal(t, next, gridToString(s.Next2D(grid)), fmt.Sprintf("testcase: %d", index+1))
+	}
+}
+
+func TestMarqueeSchedule_Scaled(t *testing.T) {
+	var s schedule.MarqueeSchedule
+	s.SetMessage("E")
+
+	// three rows show the top, middle and bottom row of the font
+	for range 2 {
+		_ = s.Next2D(schedule.Grid{Rows: 3, Columns: 3})
+	}
+	assert.Equal(t, "111/110/111", gridToString(s.Next2D(schedule.Grid{Rows: 3, Columns: 3})))
+
+	// seven rows center the font
+	s.SetMessage("E")
+	for range 2 {
+		_ = s.Next2D(schedule.Grid{Rows: 7, Columns: 3})
+	}
+	assert.Equal(t, "000/111/100/110/100/111/000", gridToString(s.Next2D(schedule.Grid{Rows: 7, Columns: 3})))
+
+	// a single row shows the middle row of the font
+	s.SetMessage("E")
+	for range 2 {
+		_ = s.Next(3)
+	}
+	assert.Equal(t, "110", boolToString(s.Next(3)))
+}
+
+func TestMarqueeSchedule_NoMessage(t *testing.T) {
+	var s schedule.MarqueeSchedule
+	assert.Equal(t, "000/000", gridToString(s.Next2D(schedule.Grid{Rows: 2, Columns: 3})))
+}
@@ -0,0 +1,102 @@
+package schedule
+
+import (
+	"strings"
+)
+
+// MessageSchedule is implemented by schedules that display a text message.
+type MessageSchedule interface {
+	SetMessage(message string)
+}
+
+// MorseSchedule flashes a message in Morse code. Each call to Next advances one Morse unit: a dot is one unit on,
+// a dash three units on. Parts of a letter are separated by one unit off, letters by three units and words by seven.
+// After the message, the schedule waits seven units and repeats the message.
+//
+// By default, all LEDs show the message. If Node is set, only the LED at that position in the pattern does, counting
+// from 1. Node is a position, not a node name.
+type MorseSchedule struct {
+	sequence []bool
+	message  string
+	Node     int
+	index    int
+}
+
+var _ Schedule = &MorseSchedule{}
+var _ MessageSchedule = &MorseSchedule{}
+var _ Stateful = &MorseSchedule{}
+
+// SetMessage sets the message to display. The message restarts from the beginning.
+func (s *MorseSchedule) SetMessage(message string) {
+	s.message = message
+	s.sequence = morseSequence(message)
+	s.index = 0
+}
+
+// Next returns the next pattern
+func (s *MorseSchedule) Next(count int) []bool {
+	next := make([]bool, max(count, 0))
+	if len(s.sequence) == 0 {
+		return next
+	}
+	s.index %= len(s.sequence)
+	on := s.sequence[s.index]
+	s.index++
+	for i := range next {
+		next[i] = on && (s.Node <= 0 || s.Node == i+1)
+	}
+	return next
+}
+
+// MarshalState returns the position in the message.
+func (s *MorseSchedule) MarshalState() ([]byte, error) {
+	return marshalIndex(s.index)
+}
+
+// UnmarshalState restores the position in the message.
+func (s *MorseSchedule) UnmarshalState(data []byte) (err error) {
+	s.index, err = unmarshalIndex(data)
+	return err
+}
+
+var morseCode = map[rune]string{
+	'A': ".-", 'B': "-...", 'C': "-.-.", 'D': "-..", 'E': ".", 'F': "..-.", 'G': "--.", 'H': "....", 'I': "..",
+	'J': ".---", 'K': "-.-", 'L': ".-..", 'M': "--", 'N': "-.", 'O': "---", 'P': ".--.", 'Q': "--.-", 'R': ".-.",
+	'S': "...", 'T': "-", 'U': "..-", 'V': "...-", 'W': ".--", 'X': "-..-", 'Y': "-.--", 'Z': "--..",
+	'0': "-----", '1': ".----", '2': "..---", '3': "...--", '4': "....-", '5': ".....", '6': "-....", '7': "--...",
+	'8': "---..", '9': "----.", '.': ".-.-.-", ',': "--..--", '?': "..--..", '!': "-.-.--", '-': "-....-",
+	'/': "-..-.", '@': ".--.-.", '=': "-...-", ':': "---...",
+}
+
+// morseSequence returns the LED states for the message, one per Morse unit, including the gap before the message repeats.
+// Characters without a Morse code are ignored.
+func morseSequence(message string) []bool {
+	var sequence []bool
+	for _, word := range strings.Fields(strings.ToUpper(message)) {
+		var letters []string
+		for _, r := range word {
+			if code, ok := morseCode[r]; ok {
+				letters = append(letters, code)
+			}
+		}
+		if len(letters) == 0 {
+			continue
+		}
+		for i, code := range letters {
+			if i > 0 {
+				sequence = append(sequence, false, false, false)
+			}
+			for j, symbol := range code {
+				if j > 0 {
+					sequence = append(sequence, false)
+				}
+				sequence = append(sequence, true)
+				if symbol == '-' {
+					sequence = append(sequence, true, true)
+				}
+			}
+		}
+		sequence = append(sequence, false, false, false, false, false, false, false)
+	}
+	return sequence
+}
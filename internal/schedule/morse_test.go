@@ -0,0 +1,48 @@
+package schedule_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMorseSchedule(t *testing.T) {
+	var s schedule.MorseSchedule
+	s.SetMessage("sos")
+
+	// S: ... O: --- S: ... followed by the gap before the message repeats
+	const sos = "10101" + "000" + "11101110111" + "000" + "10101" + "0000000"
+	var got strings.Builder
+	for range 2 * len(sos) {
+		next := s.Next(2)
+		assert.Equal(t, next[0], next[1])
+		got.WriteString(boolToString(next[:1]))
+	}
+	assert.Equal(t, sos+sos, got.String())
+}
+
+func TestMorseSchedule_Words(t *testing.T) {
+	var s schedule.MorseSchedule
+	s.SetMessage("e e #")
+
+	const want = "1" + "0000000" + "1" + "0000000"
+	var got strings.Builder
+	for range len(want) {
+		got.WriteString(boolToString(s.Next(1)))
+	}
+	assert.Equal(t, want, got.String())
+}
+
+func TestMorseSchedule_Node(t *testing.T) {
+	s, err := schedule.New("morse", schedule.WithMessage("E"), schedule.WithMorseNode(2))
+	assert.NoError(t, err)
+	assert.Equal(t, "0100", boolToString(s.Next(4)))
+	assert.Equal(t, "0000", boolToString(s.Next(4)))
+}
+
+func TestMorseSchedule_NoMessage(t *testing.T) {
+	var s schedule.MorseSchedule
+	assert.Equal(t, "000", boolToString(s.Next(3)))
+}
@@ -1,26 +1,107 @@
 package schedule
 
 import (
-	"math/rand"
+	"math/rand/v2"
+	"slices"
 )
 
-// RandomSchedule switches on a LED at random
+// RandomAware is implemented by schedules that use random numbers. SetSource sets the source of the random numbers,
+// so the schedule's patterns can be reproduced.
+type RandomAware interface {
+	SetSource(source rand.Source)
+}
+
+// randomSource provides random numbers to a schedule. The zero value uses the global random number generator.
+type randomSource struct {
+	rand *rand.Rand
+}
+
+var _ RandomAware = &randomSource{}
+
+// SetSource sets the source of the random numbers
+func (r *randomSource) SetSource(source rand.Source) {
+	r.rand = rand.New(source)
+}
+
+func (r *randomSource) random() *rand.Rand {
+	if r.rand == nil {
+		r.rand = rand.New(globalSource{})
+	}
+	return r.rand
+}
+
+// globalSource is a rand.Source that reads from the global random number generator.
+type globalSource struct{}
+
+func (globalSource) Uint64() uint64 {
+	return rand.Uint64()
+}
+
+// RandomSchedule switches on a random set of LEDs. Each pattern is chosen uniformly from all possible patterns,
+// other than the previous one. With no nodes, it returns an empty pattern.
 type RandomSchedule struct {
-	current int
+	randomSource
+	current []bool
 }
 
 var _ Schedule = &RandomSchedule{}
 
 // Next returns the next pattern
 func (s *RandomSchedule) Next(count int) []bool {
-	var next int
-	maxVal := 1<<(count-1) + 1
-	for range 5 {
-		next = rand.Intn(maxVal)
-		if next != s.current {
-			break
-		}
+	count = max(count, 0)
+	next := s.randomBits(count)
+	for count > 0 && slices.Equal(next, s.current) {
+		next = s.randomBits(count)
 	}
 	s.current = next
-	return intToBits(s.current, count)
+	return slices.Clone(s.current)
+}
+
+// randomBits returns count independent, uniformly distributed bits.
+func (s *RandomSchedule) randomBits(count int) []bool {
+	bits := make([]bool, count)
+	var word uint64
+	for i := range bits {
+		if i%64 == 0 {
+			word = s.random().Uint64()
+		}
+		bits[i] = word&0x1 == 0x1
+		word >>= 1
+	}
+	return bits
+}
+
+// SparkleSchedule switches on each LED independently, with a fixed probability.
+type SparkleSchedule struct {
+	randomSource
+	// Probability is the probability that a LED is switched on, between 0 and 1.
+	Probability float64
+}
+
+var _ Schedule = &SparkleSchedule{}
+
+// Next returns the next pattern
+func (s *SparkleSchedule) Next(count int) []bool {
+	bits := make([]bool, max(count, 0))
+	for i := range bits {
+		bits[i] = s.random().Float64() < s.Probability
+	}
+	return bits
+}
+
+// ExactlyKSchedule switches on K LEDs, chosen at random. If there are fewer than K nodes, all LEDs are switched on.
+type ExactlyKSchedule struct {
+	randomSource
+	K int
+}
+
+var _ Schedule = &ExactlyKSchedule{}
+
+// Next returns the next pattern
+func (s *ExactlyKSchedule) Next(count int) []bool {
+	bits := make([]bool, max(count, 0))
+	for _, i := range s.random().Perm(len(bits))[:min(max(s.K, 0), len(bits))] {
+		bits[i] = true
+	}
+	return bits
 }
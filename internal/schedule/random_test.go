@@ -1,10 +1,14 @@
 package schedule_test
 
 import (
+	"math/rand/v2"
+	"slices"
 	"testing"
+	"testing/quick"
 
 	"github.com/clambin/ledswitcher/internal/schedule"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRandomScheduler_Schedule(t *testing.T) {
@@ -13,3 +17,134 @@ func TestRandomScheduler_Schedule(t *testing.T) {
 		assert.Len(t, s.Next(4), 4)
 	}
 }
+
+func TestRandomSchedule_Properties(t *testing.T) {
+	// patterns have the requested width and never repeat
+	f := func(width uint16) bool {
+		count := int(width % 300)
+		var s schedule.RandomSchedule
+		previous := s.Next(count)
+		for range 10 {
+			next := s.Next(count)
+			if len(next) != count || count > 0 && slices.Equal(next, previous) {
+				return false
+			}
+			previous = next
+		}
+		return true
+	}
+	assert.NoError(t, quick.Check(f, nil))
+}
+
+func TestRandomSchedule_Uniform(t *testing.T) {
+	// every pattern is equally likely
+	const count, samples = 3, 8000
+	var s schedule.RandomSchedule
+	patterns := make(map[string]int)
+	for range samples {
+		patterns[boolToString(s.Next(count))]++
+	}
+	assert.Len(t, patterns, 1<<count)
+	for pattern, n := range patterns {
+		assert.InDelta(t, samples>>count, n, 200, pattern)
+	}
+
+	// every LED is lit about half the time, including the high bits of wide patterns
+	const wide = 300
+	lit := make([]int, wide)
+	for range 1000 {
+		for i, on := range s.Next(wide) {
+			if on {
+				lit[i]++
+			}
+		}
+	}
+	for i, n := range lit {
+		assert.InDelta(t, 500, n, 100, i)
+	}
+}
+
+func TestRandomSchedule_NoNodes(t *testing.T) {
+	var s schedule.RandomSchedule
+	assert.Empty(t, s.Next(0))
+	assert.Empty(t, s.Next(0))
+}
+
+func TestRandomSchedules_Seeded(t *testing.T) {
+	for _, mode := range []string{"random", "sparkle", "exactly-k-on", "life"} {
+		t.Run(mode, func(t *testing.T) {
+			// schedules with the same seed produce the same patterns
+			s1, err := schedule.New(mode, schedule.WithSource(rand.NewPCG(1, 2)))
+			require.NoError(t, err)
+			s2, err := schedule.New(mode, schedule.WithSource(rand.NewPCG(1, 2)))
+			require.NoError(t, err)
+			for range 20 {
+				assert.Equal(t, s1.Next(16), s2.Next(16))
+			}
+		})
+	}
+}
+
+func TestSparkleSchedule(t *testing.T) {
+	tests := []struct {
+		name        string
+		probability float64
+		want        float64
+	}{
+		{name: "never", probability: 0, want: 0},
+		{name: "default", probability: schedule.DefaultProbability, want: schedule.DefaultProbability},
+		{name: "half", probability: 0.5, want: 0.5},
+		{name: "always", probability: 1, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := schedule.New("sparkle", schedule.WithProbability(tt.probability), schedule.WithSource(rand.NewPCG(1, 2)))
+			require.NoError(t, err)
+			const count, samples = 10, 1000
+			var lit int
+			for range samples {
+				next := s.Next(count)
+				require.Len(t, next, count)
+				lit += countLit(next)
+			}
+			assert.InDelta(t, tt.want, float64(lit)/(count*samples), 0.02)
+		})
+	}
+}
+
+func TestExactlyKSchedule(t *testing.T) {
+	tests := []struct {
+		name  string
+		k     int
+		count int
+		want  int
+	}{
+		{name: "one", k: 1, count: 5, want: 1},
+		{name: "some", k: 3, count: 5, want: 3},
+		{name: "all", k: 5, count: 5, want: 5},
+		{name: "too few nodes", k: 7, count: 5, want: 5},
+		{name: "none", k: 0, count: 5, want: 0},
+		{name: "no nodes", k: 3, count: 0, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := schedule.New("exactly-k-on", schedule.WithK(tt.k), schedule.WithSource(rand.NewPCG(1, 2)))
+			require.NoError(t, err)
+			for range 20 {
+				next := s.Next(tt.count)
+				require.Len(t, next, tt.count)
+				assert.Equal(t, tt.want, countLit(next))
+			}
+		})
+	}
+}
+
+func countLit(bits []bool) int {
+	var count int
+	for _, on := range bits {
+		if on {
+			count++
+		}
+	}
+	return count
+}
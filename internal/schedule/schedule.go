@@ -1,8 +1,11 @@
 package schedule
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/rand/v2"
 	"slices"
+	"time"
 )
 
 // Schedule interface to determine the next LED to switch on
@@ -10,8 +13,120 @@ type Schedule interface {
 	Next(count int) []bool
 }
 
+// Capabilities describes the LED capabilities of a node.
+type Capabilities struct {
+	Triggers      []string `json:"triggers,omitempty"`
+	LEDs          int      `json:"leds"`
+	MaxBrightness int      `json:"maxBrightness"`
+}
+
+// Equal reports whether two Capabilities are the same.
+func (c Capabilities) Equal(other Capabilities) bool {
+	return c.LEDs == other.LEDs && c.MaxBrightness == other.MaxBrightness && slices.Equal(c.Triggers, other.Triggers)
+}
+
+// CapabilityAware is implemented by schedules that adapt their pattern to the capabilities of the nodes.
+// Before each call to Next, SetCapabilities receives the capabilities of each node, in pattern order.
+type CapabilityAware interface {
+	SetCapabilities(capabilities []Capabilities)
+}
+
+// Resizer is implemented by schedules whose state depends on the number of nodes. When the number of nodes changes,
+// Resize receives the old and new number of nodes before the next call to Next, so the schedule can continue its
+// pattern smoothly.
+type Resizer interface {
+	Resize(oldCount, newCount int)
+}
+
+// Stateful is implemented by schedules that can save their position in the pattern. A schedule restored with
+// UnmarshalState continues the pattern where the saved schedule left off. UnmarshalState must be called on
+// a schedule created with the same mode and options as the saved schedule.
+type Stateful interface {
+	MarshalState() ([]byte, error)
+	UnmarshalState(data []byte) error
+}
+
+// DefaultProbability is the default probability that a LED is switched on, for the sparkle mode.
+const DefaultProbability = 0.25
+
+// An Option configures a Schedule created by New. Options that don't apply to the schedule's mode are ignored.
+type Option func(Schedule)
+
+// WithMessage sets the message for schedules that display a text message (see MessageSchedule).
+func WithMessage(message string) Option {
+	return func(s Schedule) {
+		if m, ok := s.(MessageSchedule); ok {
+			m.SetMessage(message)
+		}
+	}
+}
+
+// WithMorseNode sets the position of the node that shows the Morse code, counting from 1 in pattern order. The schedule
+// doesn't know the node names: to select a node by name, use an explicit node order. If zero, all nodes show the message.
+func WithMorseNode(node int) Option {
+	return func(s Schedule) {
+		if m, ok := s.(*MorseSchedule); ok {
+			m.Node = node
+		}
+	}
+}
+
+// WithLocation sets the time zone for schedules that show the time.
+func WithLocation(location *time.Location) Option {
+	return func(s Schedule) {
+		if c, ok := s.(*BinaryClockSchedule); ok {
+			c.Location = location
+		}
+	}
+}
+
+// WithClock sets the clock for schedules that show the time.
+func WithClock(clock Clock) Option {
+	return func(s Schedule) {
+		if c, ok := s.(*BinaryClockSchedule); ok {
+			c.Clock = clock
+		}
+	}
+}
+
+// WithSource sets the source of random numbers for schedules that use random numbers (see RandomAware).
+func WithSource(source rand.Source) Option {
+	return func(s Schedule) {
+		if r, ok := s.(RandomAware); ok {
+			r.SetSource(source)
+		}
+	}
+}
+
+// WithProbability sets the probability that a LED is switched on, for the sparkle mode.
+func WithProbability(probability float64) Option {
+	return func(s Schedule) {
+		if sparkle, ok := s.(*SparkleSchedule); ok {
+			sparkle.Probability = probability
+		}
+	}
+}
+
+// WithK sets the number of LEDs that are switched on, for the exactly-k-on mode.
+func WithK(k int) Option {
+	return func(s Schedule) {
+		if e, ok := s.(*ExactlyKSchedule); ok {
+			e.K = k
+		}
+	}
+}
+
+// WithTaps sets the taps of the feedback polynomial, for the lfsr mode.
+func WithTaps(taps []int) Option {
+	return func(s Schedule) {
+		if l, ok := s.(*LFSRSchedule); ok {
+			l.Taps = taps
+		}
+	}
+}
+
 // New creates a new Schedule for the specified mode
-func New(mode string) (Schedule, error) {
+func New(mode string, options ...Option) (Schedule, error) {
 	var s Schedule
 	switch mode {
 	case "linear":
@@ -20,13 +135,50 @@ func New(mode string) (Schedule, error) {
 		s = &AlternatingSchedule{}
 	case "random":
 		s = &RandomSchedule{}
+	case "sparkle":
+		s = &SparkleSchedule{Probability: DefaultProbability}
+	case "exactly-k-on":
+		s = &ExactlyKSchedule{K: 1}
 	case "binary":
 		s = &BinarySchedule{}
 	case "reverse-binary":
 		s = &ReverseBinarySchedule{}
+	case "gray":
+		s = &GrayCodeSchedule{}
+	case "johnson":
+		s = &JohnsonSchedule{}
+	case "lfsr":
+		s = &LFSRSchedule{}
+	case "fibonacci":
+		s = &FibonacciSchedule{}
+	case "prime":
+		s = &PrimeSchedule{}
+	case "row-sweep":
+		s = &RowSweepSchedule{}
+	case "column-sweep":
+		s = &ColumnSweepSchedule{}
+	case "diagonal":
+		s = &DiagonalWaveSchedule{}
+	case "spiral":
+		s = &SpiralSchedule{}
+	case "life":
+		s = &LifeSchedule{}
+	case "morse":
+		s = &MorseSchedule{}
+	case "marquee":
+		s = &MarqueeSchedule{}
+	case "clock":
+		s = &BinaryClockSchedule{Format: ClockBinary}
+	case "clock-bcd":
+		s = &BinaryClockSchedule{Format: ClockBCD}
+	case "clock-unix":
+		s = &BinaryClockSchedule{Format: ClockUnix}
 	default:
 		return nil, fmt.Errorf("invalid schedule: %s", mode)
 	}
+	for _, option := range options {
+		option(s)
+	}
 	return s, nil
 }
 
@@ -39,3 +191,23 @@ func intToBits(val, count int) []bool {
 	slices.Reverse(bits)
 	return bits
 }
+
+// indexState is the state of schedules that only track their position in the pattern.
+type indexState struct {
+	Index int `json:"index"`
+}
+
+func marshalIndex(index int) ([]byte, error) {
+	return json.Marshal(indexState{Index: index})
+}
+
+func unmarshalIndex(data []byte) (int, error) {
+	var state indexState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return 0, err
+	}
+	if state.Index < 0 {
+		return 0, fmt.Errorf("invalid index: %d", state.Index)
+	}
+	return state.Index, nil
+}
@@ -1,9 +1,11 @@
 package schedule
 
 import (
+	"math/rand/v2"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNew(t *testing.T) {
@@ -14,8 +16,25 @@ func TestNew(t *testing.T) {
 		{name: "linear", want: assert.NoError},
 		{name: "alternating", want: assert.NoError},
 		{name: "random", want: assert.NoError},
+		{name: "sparkle", want: assert.NoError},
+		{name: "exactly-k-on", want: assert.NoError},
 		{name: "binary", want: assert.NoError},
 		{name: "reverse-binary", want: assert.NoError},
+		{name: "gray", want: assert.NoError},
+		{name: "johnson", want: assert.NoError},
+		{name: "lfsr", want: assert.NoError},
+		{name: "fibonacci", want: assert.NoError},
+		{name: "prime", want: assert.NoError},
+		{name: "row-sweep", want: assert.NoError},
+		{name: "column-sweep", want: assert.NoError},
+		{name: "diagonal", want: assert.NoError},
+		{name: "spiral", want: assert.NoError},
+		{name: "life", want: assert.NoError},
+		{name: "morse", want: assert.NoError},
+		{name: "marquee", want: assert.NoError},
+		{name: "clock", want: assert.NoError},
+		{name: "clock-bcd", want: assert.NoError},
+		{name: "clock-unix", want: assert.NoError},
 		{name: "", want: assert.Error},
 		{name: "invalid", want: assert.Error},
 	}
@@ -44,3 +63,63 @@ func Test_intToBits(t *testing.T) {
 		assert.Equal(t, tt.want, intToBits(tt.val, tt.len))
 	}
 }
+
+func TestStateful(t *testing.T) {
+	// life is tested in TestLifeSchedule_State: its reseeds depend on the source of random numbers
+	modes := []string{
+		"linear", "alternating", "binary", "reverse-binary", "gray", "johnson", "lfsr", "fibonacci", "prime",
+		"row-sweep", "column-sweep", "diagonal", "spiral", "morse", "marquee",
+	}
+	const count = 6
+	grid := Grid{Rows: 2, Columns: 3}
+	next := func(s Schedule) any {
+		if s2D, ok := s.(Schedule2D); ok {
+			return s2D.Next2D(grid)
+		}
+		return s.Next(count)
+	}
+	for _, mode := range modes {
+		t.Run(mode, func(t *testing.T) {
+			newSchedule := func() Schedule {
+				s, err := New(mode, WithMessage("SOS"), WithSource(rand.NewPCG(1, 2)))
+				require.NoError(t, err)
+				return s
+			}
+			original := newSchedule()
+			for range 5 {
+				next(original)
+			}
+			state, err := original.(Stateful).MarshalState()
+			require.NoError(t, err)
+
+			restored := newSchedule()
+			require.NoError(t, restored.(Stateful).UnmarshalState(state))
+			for i := range 10 {
+				require.Equal(t, next(original), next(restored), i)
+			}
+
+			assert.Error(t, restored.(Stateful).UnmarshalState([]byte("not json")))
+		})
+	}
+}
+
+func TestStateful_Invalid(t *testing.T) {
+	tests := []struct {
+		schedule Stateful
+		state    string
+	}{
+		{schedule: &LinearSchedule{}, state: `{"index":-1}`},
+		{schedule: &AlternatingSchedule{}, state: `{"index":1,"direction":2}`},
+		{schedule: &AlternatingSchedule{}, state: `{"index":2,"direction":0}`},
+		{schedule: &LFSRSchedule{}, state: `{"register":[false,false,false]}`},
+		{schedule: &BinarySchedule{}, state: `{"current":-1}`},
+		{schedule: &FibonacciSchedule{}, state: `{"current":3,"next":2}`},
+		{schedule: &SpiralSchedule{}, state: `{"grid":{"Rows":-1,"Columns":2},"index":0}`},
+		{schedule: &SpiralSchedule{}, state: `{"grid":{"Rows":1000,"Columns":1000},"index":0}`},
+		{schedule: &SpiralSchedule{}, state: `{"grid":{"Rows":4294967296,"Columns":4294967296},"index":0}`},
+		{schedule: &LifeSchedule{}, state: `{"current":[[true]],"grid":{"Rows":2,"Columns":2}}`},
+	}
+	for _, tt := range tests {
+		assert.Error(t, tt.schedule.UnmarshalState([]byte(tt.state)), tt.state)
+	}
+}
@@ -0,0 +1,103 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SpiralSchedule moves the active LED along a clockwise spiral, from the top-left corner to the center of the grid,
+// and then starts from the beginning again. Cells without a LED are skipped.
+type SpiralSchedule struct {
+	path  [][2]int
+	grid  Grid
+	index int
+}
+
+var (
+	_ Schedule2D = &SpiralSchedule{}
+	_ Stateful   = &SpiralSchedule{}
+)
+
+// Next returns the next pattern
+func (s *SpiralSchedule) Next(count int) []bool {
+	return singleRow(s, count)
+}
+
+// Next2D returns the next pattern
+func (s *SpiralSchedule) Next2D(grid Grid) [][]bool {
+	cells := newCells(grid)
+	if grid.Cells() == 0 {
+		return cells
+	}
+	if grid != s.grid {
+		s.grid = grid
+		s.path = spiral(grid)
+		s.index = 0
+	}
+	s.index %= len(s.path)
+	cell := s.path[s.index]
+	cells[cell[0]][cell[1]] = true
+	s.index++
+	return cells
+}
+
+// maxSpiralCells bounds the size of a grid restored by UnmarshalState, as the spiral holds every cell of the grid.
+const maxSpiralCells = 1 << 16
+
+type spiralState struct {
+	Grid  Grid `json:"grid"`
+	Index int  `json:"index"`
+}
+
+// MarshalState returns the position on the spiral.
+func (s *SpiralSchedule) MarshalState() ([]byte, error) {
+	return json.Marshal(spiralState{Grid: s.grid, Index: s.index})
+}
+
+// UnmarshalState restores the position on the spiral.
+func (s *SpiralSchedule) UnmarshalState(data []byte) error {
+	var state spiralState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Grid.Rows < 0 || state.Grid.Columns < 0 || state.Grid.LEDs < 0 || state.Index < 0 {
+		return fmt.Errorf("invalid state: grid %dx%d (%d leds), index %d", state.Grid.Rows, state.Grid.Columns, state.Grid.LEDs, state.Index)
+	}
+	if state.Grid.Rows > maxSpiralCells || state.Grid.Columns > maxSpiralCells || state.Grid.Cells() > maxSpiralCells {
+		return fmt.Errorf("invalid state: grid %dx%d exceeds %d cells", state.Grid.Rows, state.Grid.Columns, maxSpiralCells)
+	}
+	s.grid, s.index = state.Grid, state.Index
+	s.path = spiral(state.Grid)
+	return nil
+}
+
+// spiral returns the (row, column) coordinates of all cells in the grid that hold a LED, in clockwise spiral order.
+func spiral(grid Grid) [][2]int {
+	path := make([][2]int, 0, grid.Cells())
+	add := func(row, column int) {
+		if grid.Filled(row, column) {
+			path = append(path, [2]int{row, column})
+		}
+	}
+	top, bottom, left, right := 0, grid.Rows-1, 0, grid.Columns-1
+	for top <= bottom && left <= right {
+		for column := left; column <= right; column++ {
+			add(top, column)
+		}
+		for row := top + 1; row <= bottom; row++ {
+			add(row, right)
+		}
+		if top < bottom {
+			for column := right - 1; column >= left; column-- {
+				add(bottom, column)
+			}
+		}
+		if left < right {
+			for row := bottom - 1; row > top; row-- {
+				add(row, left)
+			}
+		}
+		top, bottom, left, right = top+1, bottom-1, left+1, right-1
+	}
+	return path
+}
@@ -0,0 +1,55 @@
+package schedule_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpiralSchedule(t *testing.T) {
+	s := schedule.SpiralSchedule{}
+	grid := schedule.Grid{Rows: 3, Columns: 3}
+
+	want := []string{
+		"100/000/000",
+		"010/000/000",
+		"001/000/000",
+		"000/001/000",
+		"000/000/001",
+		"000/000/010",
+		"000/000/100",
+		"000/100/000",
+		"000/010/000",
+		"100/000/000",
+	}
+
+	for index, next := range want {
+		assert.Equal(t, next, gridToString(s.Next2D(grid)), fmt.Sprintf("testcase: %d", index+1))
+	}
+
+	// changing the grid restarts the spiral
+	assert.Equal(t, "10/00", gridToString(s.Next2D(schedule.Grid{Rows: 2, Columns: 2})))
+	assert.Equal(t, "01/00", gridToString(s.Next2D(schedule.Grid{Rows: 2, Columns: 2})))
+	assert.Equal(t, "00/01", gridToString(s.Next2D(schedule.Grid{Rows: 2, Columns: 2})))
+	assert.Equal(t, "00/10", gridToString(s.Next2D(schedule.Grid{Rows: 2, Columns: 2})))
+}
+
+func TestSpiralSchedule_PartialGrid(t *testing.T) {
+	s := schedule.SpiralSchedule{}
+	grid := schedule.Grid{Rows: 2, Columns: 3, LEDs: 4}
+
+	// cells without a LED are skipped
+	want := []string{
+		"100/000",
+		"010/000",
+		"001/000",
+		"000/100",
+		"100/000",
+	}
+
+	for index, next := range want {
+		assert.Equal(t, next, gridToString(s.Next2D(grid)), fmt.Sprintf("testcase: %d", index+1))
+	}
+}
@@ -0,0 +1,81 @@
+package schedule
+
+// RowSweepSchedule switches on one row of LEDs at a time, moving from the top row to the bottom row.
+type RowSweepSchedule struct {
+	index int
+}
+
+var (
+	_ Schedule2D = &RowSweepSchedule{}
+	_ Stateful   = &RowSweepSchedule{}
+)
+
+// Next returns the next pattern
+func (s *RowSweepSchedule) Next(count int) []bool {
+	return singleRow(s, count)
+}
+
+// Next2D returns the next pattern
+func (s *RowSweepSchedule) Next2D(grid Grid) [][]bool {
+	cells := newCells(grid)
+	if grid.Cells() == 0 {
+		return cells
+	}
+	row := s.index % grid.Rows
+	for column := range cells[row] {
+		cells[row][column] = true
+	}
+	s.index = row + 1
+	return cells
+}
+
+// MarshalState returns the next row.
+func (s *RowSweepSchedule) MarshalState() ([]byte, error) {
+	return marshalIndex(s.index)
+}
+
+// UnmarshalState restores the next row.
+func (s *RowSweepSchedule) UnmarshalState(data []byte) (err error) {
+	s.index, err = unmarshalIndex(data)
+	return err
+}
+
+// ColumnSweepSchedule switches on one column of LEDs at a time, moving from the left column to the right column.
+type ColumnSweepSchedule struct {
+	index int
+}
+
+var (
+	_ Schedule2D = &ColumnSweepSchedule{}
+	_ Stateful   = &ColumnSweepSchedule{}
+)
+
+// Next returns the next pattern
+func (s *ColumnSweepSchedule) Next(count int) []bool {
+	return singleRow(s, count)
+}
+
+// Next2D returns the next pattern
+func (s *ColumnSweepSchedule) Next2D(grid Grid) [][]bool {
+	cells := newCells(grid)
+	if grid.Cells() == 0 {
+		return cells
+	}
+	column := s.index % grid.Columns
+	for row := range cells {
+		cells[row][column] = true
+	}
+	s.index = column + 1
+	return cells
+}
+
+// MarshalState returns the next column.
+func (s *ColumnSweepSchedule) MarshalState() ([]byte, error) {
+	return marshalIndex(s.index)
+}
+
+// UnmarshalState restores the next column.
+func (s *ColumnSweepSchedule) UnmarshalState(data []byte) (err error) {
+	s.index, err = unmarshalIndex(data)
+	return err
+}
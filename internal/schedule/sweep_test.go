@@ -0,0 +1,65 @@
+package schedule_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowSweepSchedule(t *testing.T) {
+	s := schedule.RowSweepSchedule{}
+
+	testCases := []struct {
+		grid schedule.Grid
+		next string
+	}{
+		{grid: schedule.Grid{Rows: 3, Columns: 2}, next: "11/00/00"},
+		{grid: schedule.Grid{Rows: 3, Columns: 2}, next: "00/11/00"},
+		{grid: schedule.Grid{Rows: 3, Columns: 2}, next: "00/00/11"},
+		{grid: schedule.Grid{Rows: 3, Columns: 2}, next: "11/00/00"},
+		{grid: schedule.Grid{Rows: 2, Columns: 3}, next: "000/111"},
+		{grid: schedule.Grid{Rows: 2, Columns: 3}, next: "111/000"},
+		{grid: schedule.Grid{}, next: ""},
+	}
+
+	for index, testCase := range testCases {
+		next := s.Next2D(testCase.grid)
+		assert.Equal(t, testCase.next, gridToString(next), fmt.Sprintf("testcase: %d", index+1))
+	}
+
+	assert.Equal(t, "111", boolToString(s.Next(3)))
+}
+
+func TestColumnSweepSchedule(t *testing.T) {
+	s := schedule.ColumnSweepSchedule{}
+
+	testCases := []struct {
+		grid schedule.Grid
+		next string
+	}{
+		{grid: schedule.Grid{Rows: 2, Columns: 3}, next: "100/100"},
+		{grid: schedule.Grid{Rows: 2, Columns: 3}, next: "010/010"},
+		{grid: schedule.Grid{Rows: 2, Columns: 3}, next: "001/001"},
+		{grid: schedule.Grid{Rows: 2, Columns: 3}, next: "100/100"},
+		{grid: schedule.Grid{Rows: 3, Columns: 2}, next: "01/01/01"},
+		{grid: schedule.Grid{Rows: 3, Columns: 2}, next: "10/10/10"},
+	}
+
+	for index, testCase := range testCases {
+		next := s.Next2D(testCase.grid)
+		assert.Equal(t, testCase.next, gridToString(next), fmt.Sprintf("testcase: %d", index+1))
+	}
+
+	assert.Equal(t, "0100", boolToString(s.Next(4)))
+}
+
+func gridToString(input [][]bool) string {
+	rows := make([]string, 0, len(input))
+	for _, row := range input {
+		rows = append(rows, boolToString(row))
+	}
+	return strings.Join(rows, "/")
+}
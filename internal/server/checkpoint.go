@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+)
+
+// maxCatchUp is the maximum number of rotations that are replayed to catch up with a pattern. A new leader doesn't
+// resume from an older checkpoint, but starts the pattern from the beginning. An endpoint that falls further behind
+// an epoch skips the oldest patterns.
+const maxCatchUp = 1000
+
+// checkpoint holds the position of the leader's schedule, so a new leader can continue the pattern where the previous
+// leader left off.
+type checkpoint struct {
+	At       time.Time     `json:"at"`
+	Schedule string        `json:"schedule"`
+	State    []byte        `json:"state"`
+	Nodes    int           `json:"nodes"`
+	Interval time.Duration `json:"interval"`
+}
+
+// SetCheckpoint sets how often the Leader saves the position of its schedule. When leadership moves, the new leader
+// resumes the pattern from the last checkpoint, rather than starting from the beginning. With ProtocolEpochs, the new
+// leader takes over the current epoch instead. If interval is zero, the Leader doesn't save checkpoints and a new leader
+// starts the pattern from the beginning.
+//
+// Only schedules that implement schedule.Stateful can be resumed.
+func (l *Leader) SetCheckpoint(interval time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.checkpointInterval = interval
+}
+
+// resume continues the pattern of the previous leader. It only runs the first time the Leader advances after becoming
+// leader.
+func (l *Leader) resume(ctx context.Context, nodes []string) {
+	l.lock.Lock()
+	resumed := l.resumed
+	l.resumed = true
+	enabled := l.checkpointInterval > 0
+	l.lock.Unlock()
+	if resumed || !enabled {
+		return
+	}
+	if err := l.resumeEpoch(ctx, nodes); err != nil {
+		l.logger.Warn("failed to resume epoch", "err", err)
+	}
+	if err := l.resumeSchedule(ctx); err != nil {
+		l.logger.Warn("failed to resume schedule", "err", err)
+	}
+}
+
+// resumeEpoch takes over the current epoch, if it renders the same pattern as the Leader's schedule. The endpoints then
+// keep rendering the epoch, instead of restarting the pattern. If the Leader's seed was chosen at random, the Leader
+// adopts the seed of the current epoch.
+func (l *Leader) resumeEpoch(ctx context.Context, nodes []string) error {
+	current, ok, err := l.currentEpoch(ctx)
+	if err != nil || !ok {
+		return err
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	e, ok := l.epoch(nodes)
+	if !ok {
+		return nil
+	}
+	if l.randomSeed {
+		e.Schedule.Seed = current.Schedule.Seed
+	}
+	if !current.sameSchedule(e) {
+		return nil
+	}
+	if d := *l.descriptor; d.Seed != current.Schedule.Seed {
+		d.Seed = current.Schedule.Seed
+		s, err := d.New()
+		if err != nil {
+			return err
+		}
+		l.setSchedule(s, &d)
+	}
+	l.lastEpoch = &current
+	l.logger.Info("resumed current epoch", "mode", current.Schedule.Mode, "start", current.Start)
+	return nil
+}
+
+// resumeSchedule restores the schedule from the last checkpoint, if it was saved by the same kind of schedule. It then
+// replays the rotations since the checkpoint was saved, so the pattern continues where the previous leader would have been.
+func (l *Leader) resumeSchedule(ctx context.Context) error {
+	c, ok, err := l.loadCheckpoint(ctx)
+	if err != nil || !ok {
+		return err
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	s, ok := l.schedule.(schedule.Stateful)
+	if !ok || c.Schedule != scheduleType(l.schedule) || c.Nodes <= 0 {
+		return nil
+	}
+	var rotations int
+	if c.Interval > 0 {
+		// the current rotation is the last one
+		rotations = max(int(time.Since(c.At)/c.Interval)-1, 0)
+	}
+	if rotations > maxCatchUp {
+		return nil
+	}
+	if err = s.UnmarshalState(c.State); err != nil {
+		return fmt.Errorf("restore state: %w", err)
+	}
+	l.nodeCount = c.Nodes
+	for range rotations {
+		l.layout.next(l.schedule, c.Nodes)
+	}
+	l.logger.Info("resumed schedule from checkpoint", "schedule", c.Schedule, "age", time.Since(c.At), "rotations", rotations)
+	return nil
+}
+
+// dueCheckpoint returns the checkpoint of the schedule, if the checkpoint interval has passed since the last checkpoint.
+// Must be called with l.lock held.
+func (l *Leader) dueCheckpoint(nodeCount int) (checkpoint, bool) {
+	s, ok := l.schedule.(schedule.Stateful)
+	if !ok || l.checkpointInterval <= 0 || time.Since(l.checkpointedAt) < l.checkpointInterval {
+		return checkpoint{}, false
+	}
+	state, err := s.MarshalState()
+	if err != nil {
+		l.logger.Warn("failed to save schedule state", "err", err)
+		return checkpoint{}, false
+	}
+	l.checkpointedAt = time.Now()
+	return checkpoint{
+		At:       l.checkpointedAt,
+		Schedule: scheduleType(l.schedule),
+		State:    state,
+		Nodes:    nodeCount,
+		Interval: l.ledInterval,
+	}, true
+}
+
+// scheduleType identifies the kind of schedule, so a checkpoint is only restored by the schedule that saved it.
+func scheduleType(s Schedule) string {
+	return fmt.Sprintf("%T", s)
+}
@@ -1,49 +1,355 @@
 package server
 
 import (
+	"cmp"
 	"context"
+	"fmt"
 	"log/slog"
+	"slices"
 	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	arrivalSlackMetric = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "ledswitcher",
+		Subsystem: "endpoint",
+		Name:      "arrival_slack_seconds",
+		Help:      "Time between the arrival of timestamped LED states and the time they are applied. Negative values are late arrivals",
+		Buckets:   []float64{-.1, -.01, 0, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"node"})
+
+	lateStatesMetric = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "ledswitcher",
+		Subsystem: "endpoint",
+		Name:      "late_states_total",
+		Help:      "Number of timestamped LED states dropped because they arrived too late",
+	})
+
+	staleMessagesMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ledswitcher",
+		Subsystem: "endpoint",
+		Name:      "stale_messages_total",
+		Help:      "Number of messages ignored because they came from an older leadership term, or arrived out of order",
+	}, []string{"reason"})
 )
 
 type Endpoint struct {
 	LED
 	eventHandler
-	logger       *slog.Logger
-	nodeName     string
+	logger     *slog.Logger
+	renderer   *renderer
+	timer      *time.Timer
+	applyTimer *time.Timer
+	// watchdog fires when the leader hasn't published LED states or epochs for the Fallback's timeout
+	watchdog   *time.Timer
+	blinkTimer *time.Timer
+	fallback   atomic.Pointer[Fallback]
+	// pending are the timestamped states waiting to be applied, in order of their apply time
+	pending  []pendingState
+	fence    fence
+	nodeName string
+	// trigger is the kernel trigger that was active when the Endpoint started
+	trigger      string
+	blinkStep    int
 	currentState atomic.Bool
+	subscribed   readiness
+	// orphaned is true while the Endpoint shows its fallback behaviour
+	orphaned        bool
+	triggerRestored bool
+}
+
+// fence rejects messages from older leadership terms and messages that arrive out of order.
+type fence struct {
+	term     uint64
+	sequence uint64
+}
+
+// accept returns true if the message with the specified header should be processed. If not, reason explains why.
+func (f *fence) accept(h header) (ok bool, reason string) {
+	switch {
+	case h.Term == 0:
+		// not fenced
+		return true, ""
+	case h.Term < f.term:
+		return false, "stale_term"
+	case h.Term == f.term && h.Sequence <= f.sequence:
+		return false, "out_of_order"
+	}
+	f.term, f.sequence = h.Term, h.Sequence
+	return true, ""
+}
+
+// pendingState is an LED state to be applied at a specific time.
+type pendingState struct {
+	at    time.Time
+	state bool
 }
 
 type LED interface {
 	Set(bool) error
 }
 
+// Run sets the LED to the states published by the Leader. Timestamped states are applied at their apply time, or
+// dropped if they arrive too late. With ProtocolEpochs, the Endpoint renders the pattern
+// itself, based on the latest epoch; as soon as the Leader publishes LED states again, the Endpoint follows those.
+//
+// If the Leader stops publishing LED states, or epochs with ProtocolEpochs, the Endpoint switches to its Fallback, until
+// the Leader is back.
 func (e *Endpoint) Run(ctx context.Context) error {
 	e.logger.Debug("endpoint started")
 	defer e.logger.Debug("endpoint stopped")
 
-	ch := e.ledStates(ctx, e.logger)
+	ch, err := e.ledStates(ctx, e.logger)
+	if err != nil {
+		return fmt.Errorf("led states: %w", err)
+	}
+	epochs, err := e.epochs(ctx, e.logger)
+	if err != nil {
+		return fmt.Errorf("epochs: %w", err)
+	}
+	e.subscribed.set()
+
+	e.timer = time.NewTimer(time.Hour)
+	e.timer.Stop()
+	defer e.timer.Stop()
+	e.applyTimer = time.NewTimer(time.Hour)
+	e.applyTimer.Stop()
+	defer e.applyTimer.Stop()
+	e.watchdog = time.NewTimer(time.Hour)
+	e.watchdog.Stop()
+	defer e.watchdog.Stop()
+	e.blinkTimer = time.NewTimer(time.Hour)
+	e.blinkTimer.Stop()
+	defer e.blinkTimer.Stop()
+	if led, ok := e.LED.(TriggerLED); ok {
+		if trigger, err := led.GetActiveMode(); err == nil {
+			e.trigger = trigger
+		}
+	}
+	e.resetWatchdog()
+	if current, ok, err := e.currentEpoch(ctx); err != nil {
+		e.logger.Warn("failed to get current epoch", "err", err)
+	} else if ok {
+		e.setEpoch(current)
+	}
+
 	for {
 		select {
-		case states, ok := <-ch:
+		case update, ok := <-ch:
 			if !ok {
 				e.logger.Warn("redis subscription closed")
 				return nil
 			}
-			e.logger.Debug("event received", "states", states, "state", e.currentState.Load())
-			desiredState := states[e.nodeName]
-			if e.currentState.Load() == desiredState {
-				//e.logger.Debug("led already in desired state", "state", desiredState)
+			e.logger.Debug("event received", "states", update.States, "applyAt", update.ApplyAt, "state", e.currentState.Load())
+			if !e.accept(update.header) {
 				continue
 			}
-			//e.logger.Debug("state changed", "state", desiredState)
-			if err := e.Set(desiredState); err != nil {
-				e.logger.Error("failed to set LED state", "err", err)
+			e.resetWatchdog()
+			if e.renderer != nil {
+				e.logger.Debug("leader publishes led states. stopping local rendering")
+				e.renderer = nil
+				e.timer.Stop()
+			}
+			e.update(update)
+		case <-e.applyTimer.C:
+			e.applyPending(time.Now())
+		case <-e.watchdog.C:
+			e.fallBack()
+		case <-e.blinkTimer.C:
+			e.blink()
+		case current, ok := <-epochs:
+			if !ok {
+				e.logger.Warn("redis subscription closed")
+				return nil
+			}
+			e.setEpoch(current)
+		case <-e.timer.C:
+			if e.renderer == nil {
 				continue
 			}
-			e.currentState.Store(desiredState)
+			if state, ok := e.renderer.render(time.Now()); ok {
+				e.setState(state)
+			}
+			e.timer.Reset(time.Until(e.renderer.next()))
 		case <-ctx.Done():
 			return nil
 		}
 	}
 }
+
+// accept checks the header of a message. Stale messages are logged and counted.
+func (e *Endpoint) accept(h header) bool {
+	ok, reason := e.fence.accept(h)
+	if !ok {
+		staleMessagesMetric.WithLabelValues(reason).Inc()
+		e.logger.Debug("ignoring stale message", "reason", reason, "leader", h.Leader, "term", h.Term, "seq", h.Sequence)
+	}
+	return ok
+}
+
+// setEpoch starts rendering the pattern of a new epoch. The Leader republishes the current epoch at every rotation,
+// so every epoch, new or not, shows that the Leader is alive.
+func (e *Endpoint) setEpoch(current epoch) {
+	if !e.accept(current.header) {
+		return
+	}
+	e.resetWatchdog()
+	if e.renderer != nil && e.renderer.Start.Equal(current.Start) && e.renderer.sameSchedule(current) {
+		return
+	}
+	r, err := newRenderer(current, e.nodeName)
+	if err != nil {
+		e.logger.Error("invalid epoch. ignoring", "err", err)
+		return
+	}
+	e.logger.Debug("new epoch received", "mode", current.Schedule.Mode, "start", current.Start)
+	e.renderer = r
+	e.timer.Reset(time.Until(r.Start))
+}
+
+// update applies the node's state in a stateUpdate, either immediately, or at the update's apply time.
+func (e *Endpoint) update(update stateUpdate) {
+	state := update.States[e.nodeName]
+	if update.ApplyAt.IsZero() {
+		// the immediate state replaces any older states that are still waiting to be applied
+		e.pending = nil
+		e.applyTimer.Stop()
+		e.setState(state)
+		return
+	}
+	slack := time.Until(update.ApplyAt)
+	arrivalSlackMetric.WithLabelValues(e.nodeName).Observe(slack.Seconds())
+	if slack < 0 {
+		lateStatesMetric.Inc()
+		e.logger.Debug("led states arrived too late. dropping", "slack", slack)
+		return
+	}
+	e.pending = append(e.pending, pendingState{at: update.ApplyAt, state: state})
+	slices.SortStableFunc(e.pending, func(a, b pendingState) int { return a.at.Compare(b.at) })
+	e.applyTimer.Reset(time.Until(e.pending[0].at))
+}
+
+// applyPending applies all pending states that are due.
+func (e *Endpoint) applyPending(now time.Time) {
+	for len(e.pending) > 0 && !e.pending[0].at.After(now) {
+		e.setState(e.pending[0].state)
+		e.pending = e.pending[1:]
+	}
+	if len(e.pending) > 0 {
+		e.applyTimer.Reset(e.pending[0].at.Sub(now))
+	}
+}
+
+func (e *Endpoint) setState(desiredState bool) {
+	if e.currentState.Load() == desiredState {
+		//e.logger.Debug("led already in desired state", "state", desiredState)
+		return
+	}
+	//e.logger.Debug("state changed", "state", desiredState)
+	if err := e.Set(desiredState); err != nil {
+		e.logger.Error("failed to set LED state", "err", err)
+		return
+	}
+	e.currentState.Store(desiredState)
+}
+
+// SetFallback sets what the Endpoint shows when the leader goes silent. The new Fallback takes effect when the next LED
+// states arrive.
+func (e *Endpoint) SetFallback(f Fallback) {
+	e.fallback.Store(&f)
+}
+
+func (e *Endpoint) getFallback() Fallback {
+	if f := e.fallback.Load(); f != nil {
+		return *f
+	}
+	return Fallback{}
+}
+
+// resetWatchdog restarts the time the Endpoint waits for the next LED states. If the Endpoint had fallen back,
+// it resumes following the leader.
+func (e *Endpoint) resetWatchdog() {
+	if e.orphaned {
+		e.resume()
+	}
+	if f := e.getFallback(); f.enabled() {
+		e.watchdog.Reset(f.Timeout)
+	} else {
+		e.watchdog.Stop()
+	}
+}
+
+// fallBack switches to the fallback behaviour.
+func (e *Endpoint) fallBack() {
+	f := e.getFallback()
+	if !f.enabled() {
+		return
+	}
+	e.logger.Warn("no led states received from leader. falling back", "mode", f.Mode, "timeout", f.Timeout)
+	e.orphaned = true
+	fallbackMetric.Set(1)
+	e.pending = nil
+	e.applyTimer.Stop()
+	// stop rendering the last epoch: it starts again, from the current pattern, when the leader is back
+	e.renderer = nil
+	e.timer.Stop()
+	switch f.Mode {
+	case FallbackOff:
+		e.setState(false)
+	case FallbackOn:
+		e.setState(true)
+	case FallbackTrigger:
+		e.restoreTrigger(cmp.Or(f.Trigger, e.trigger))
+	case FallbackOrphaned:
+		e.blinkStep = 0
+		e.blink()
+	}
+}
+
+func (e *Endpoint) restoreTrigger(trigger string) {
+	led, ok := e.LED.(TriggerLED)
+	if !ok || trigger == "" {
+		e.logger.Warn("led doesn't support kernel triggers. keeping current state")
+		return
+	}
+	if err := led.SetActiveMode(trigger); err != nil {
+		e.logger.Error("failed to restore led trigger", "trigger", trigger, "err", err)
+		return
+	}
+	e.triggerRestored = true
+}
+
+// blink shows the next step of the orphaned pattern.
+func (e *Endpoint) blink() {
+	if !e.orphaned {
+		return
+	}
+	step := orphanedBlink[e.blinkStep%len(orphanedBlink)]
+	e.blinkStep++
+	e.setState(step.state)
+	e.blinkTimer.Reset(step.duration)
+}
+
+// resume ends the fallback behaviour.
+func (e *Endpoint) resume() {
+	e.logger.Info("leader is back. resuming")
+	e.orphaned = false
+	fallbackMetric.Set(0)
+	e.blinkTimer.Stop()
+	if e.triggerRestored {
+		e.triggerRestored = false
+		if err := e.LED.(TriggerLED).SetActiveMode("none"); err != nil {
+			e.logger.Error("failed to clear led trigger", "err", err)
+		}
+		// the kernel switches the LED off when the trigger is removed
+		e.currentState.Store(false)
+	}
+}
+
+// Ready returns a channel that is closed once the Endpoint's subscriptions are live.
+func (e *Endpoint) Ready() <-chan struct{} {
+	return e.subscribed.ready()
+}
@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -23,9 +25,264 @@ func TestEndpoint_Run(t *testing.T) {
 		require.NoError(t, ep.Run(ctx))
 	}()
 
-	_ = ep.publishLEDStates(ctx, map[string]bool{"localhost": true})
+	_ = ep.publishLEDStates(ctx, stateUpdate{States: ledStates{"localhost": true}})
 	assert.Eventually(t, led.get, time.Second, 10*time.Millisecond)
 
-	_ = ep.publishLEDStates(ctx, map[string]bool{"localhost": false})
+	_ = ep.publishLEDStates(ctx, stateUpdate{States: ledStates{"localhost": false}})
 	assert.Eventually(t, func() bool { return !led.get() }, time.Second, 10*time.Millisecond)
 }
+
+func TestEndpoint_Run_Timestamped(t *testing.T) {
+	var led fakeLED
+	ep := Endpoint{
+		nodeName:     "localhost",
+		eventHandler: &fakeEventHandler{},
+		LED:          &led,
+		logger:       slog.New(slog.DiscardHandler),
+	}
+
+	ctx := t.Context()
+	go func() {
+		require.NoError(t, ep.Run(ctx))
+	}()
+
+	// states are applied at their apply time
+	late := testutil.ToFloat64(lateStatesMetric)
+	applyAt := time.Now().Add(200 * time.Millisecond)
+	_ = ep.publishLEDStates(ctx, stateUpdate{States: ledStates{"localhost": true}, ApplyAt: applyAt})
+	assert.Eventually(t, led.get, time.Second, time.Millisecond)
+	assert.False(t, time.Now().Before(applyAt))
+
+	// late states are dropped
+	_ = ep.publishLEDStates(ctx, stateUpdate{States: ledStates{"localhost": false}, ApplyAt: time.Now().Add(-time.Second)})
+	assert.Eventually(t, func() bool { return testutil.ToFloat64(lateStatesMetric) == late+1 }, time.Second, 10*time.Millisecond)
+	assert.True(t, led.get())
+}
+
+func TestEndpoint_Run_Stale(t *testing.T) {
+	var led fakeLED
+	ep := Endpoint{
+		nodeName:     "localhost",
+		eventHandler: &fakeEventHandler{},
+		LED:          &led,
+		logger:       slog.New(slog.DiscardHandler),
+	}
+
+	ctx := t.Context()
+	go func() {
+		require.NoError(t, ep.Run(ctx))
+	}()
+
+	stale := testutil.ToFloat64(staleMessagesMetric.WithLabelValues("stale_term"))
+	_ = ep.publishLEDStates(ctx, stateUpdate{States: ledStates{"localhost": true}, header: header{Leader: "node2", Term: 2, Sequence: 1}})
+	assert.Eventually(t, led.get, time.Second, 10*time.Millisecond)
+
+	// the previous leader is ignored
+	_ = ep.publishLEDStates(ctx, stateUpdate{States: ledStates{"localhost": false}, header: header{Leader: "node1", Term: 1, Sequence: 10}})
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(staleMessagesMetric.WithLabelValues("stale_term")) == stale+1
+	}, time.Second, 10*time.Millisecond)
+	assert.True(t, led.get())
+}
+
+func TestFence(t *testing.T) {
+	var f fence
+	tests := []struct {
+		name   string
+		header header
+		want   bool
+		reason string
+	}{
+		{name: "first message", header: header{Term: 2, Sequence: 5}, want: true},
+		{name: "next message", header: header{Term: 2, Sequence: 6}, want: true},
+		{name: "gap", header: header{Term: 2, Sequence: 10}, want: true},
+		{name: "duplicate", header: header{Term: 2, Sequence: 10}, want: false, reason: "out_of_order"},
+		{name: "out of order", header: header{Term: 2, Sequence: 9}, want: false, reason: "out_of_order"},
+		{name: "older term", header: header{Term: 1, Sequence: 100}, want: false, reason: "stale_term"},
+		{name: "newer term", header: header{Term: 3, Sequence: 1}, want: true},
+		{name: "not fenced", header: header{}, want: true},
+		{name: "after unfenced", header: header{Term: 3, Sequence: 2}, want: true},
+	}
+	for _, tt := range tests {
+		ok, reason := f.accept(tt.header)
+		assert.Equal(t, tt.want, ok, tt.name)
+		assert.Equal(t, tt.reason, reason, tt.name)
+	}
+}
+
+func TestEndpoint_applyPending(t *testing.T) {
+	var led fakeLED
+	ep := Endpoint{
+		nodeName:   "localhost",
+		LED:        &led,
+		logger:     slog.New(slog.DiscardHandler),
+		applyTimer: time.NewTimer(time.Hour),
+	}
+	now := time.Now()
+	ep.update(stateUpdate{States: ledStates{"localhost": false}, ApplyAt: now.Add(2 * time.Hour)})
+	ep.update(stateUpdate{States: ledStates{"localhost": true}, ApplyAt: now.Add(time.Hour)})
+
+	// pending states are applied in order of their apply time
+	ep.applyPending(now.Add(time.Hour))
+	assert.True(t, led.get())
+	assert.Len(t, ep.pending, 1)
+	ep.applyPending(now.Add(2 * time.Hour))
+	assert.False(t, led.get())
+	assert.Empty(t, ep.pending)
+
+	// immediate states replace pending states
+	ep.update(stateUpdate{States: ledStates{"localhost": false}, ApplyAt: now.Add(time.Hour)})
+	ep.update(stateUpdate{States: ledStates{"localhost": true}})
+	assert.True(t, led.get())
+	assert.Empty(t, ep.pending)
+	ep.applyPending(now.Add(time.Hour))
+	assert.True(t, led.get())
+}
+
+func TestEndpoint_Run_Epochs(t *testing.T) {
+	var led fakeLED
+	evh := fakeEventHandler{}
+	ep := Endpoint{
+		nodeName:     "node1",
+		eventHandler: &evh,
+		LED:          &led,
+		logger:       slog.New(slog.DiscardHandler),
+	}
+
+	// the current epoch is picked up at startup
+	require.NoError(t, evh.publishEpoch(t.Context(), epoch{
+		Schedule: schedule.Descriptor{Mode: "linear"},
+		Nodes:    []string{"node1"},
+		Start:    time.Now(),
+		Interval: 10 * time.Millisecond,
+	}))
+
+	ctx := t.Context()
+	go func() {
+		require.NoError(t, ep.Run(ctx))
+	}()
+	assert.Eventually(t, led.get, time.Second, 10*time.Millisecond)
+
+	// a new epoch replaces the current one
+	require.NoError(t, evh.publishEpoch(t.Context(), epoch{
+		Schedule: schedule.Descriptor{Mode: "linear"},
+		Nodes:    []string{"node0", "node1"},
+		Start:    time.Now(),
+		Interval: 10 * time.Millisecond,
+	}))
+	assert.Eventually(t, func() bool { return !led.get() }, time.Second, time.Millisecond)
+	assert.Eventually(t, led.get, time.Second, time.Millisecond)
+
+	// led states stop local rendering
+	_ = ep.publishLEDStates(ctx, stateUpdate{States: ledStates{"node1": false}})
+	assert.Eventually(t, func() bool { return !led.get() }, time.Second, 10*time.Millisecond)
+	writes := led.written()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, writes, led.written())
+}
+
+func TestEndpoint_Run_Epochs_Fallback(t *testing.T) {
+	var led fakeLED
+	evh := fakeEventHandler{}
+	ep := Endpoint{
+		nodeName:     "node1",
+		eventHandler: &evh,
+		LED:          &led,
+		logger:       slog.New(slog.DiscardHandler),
+	}
+	ep.SetFallback(Fallback{Mode: FallbackOn, Timeout: 100 * time.Millisecond})
+
+	ctx := t.Context()
+	go func() {
+		require.NoError(t, ep.Run(ctx))
+	}()
+	<-ep.Ready()
+
+	// node1 is never switched on
+	e := epoch{
+		Schedule: schedule.Descriptor{Mode: "linear"},
+		Nodes:    []string{"node1", "node2", "node3"},
+		Start:    time.Now(),
+		Interval: time.Hour,
+		header:   header{Term: 1, Sequence: 1},
+	}
+	require.NoError(t, evh.publishEpoch(ctx, e))
+
+	// while the leader republishes the epoch, the endpoint keeps rendering it
+	for range 5 {
+		time.Sleep(50 * time.Millisecond)
+		e.Sequence++
+		require.NoError(t, evh.republishEpoch(ctx, e))
+	}
+	assert.Zero(t, testutil.ToFloat64(fallbackMetric))
+	assert.False(t, led.get())
+
+	// leader goes silent
+	assert.Eventually(t, func() bool { return testutil.ToFloat64(fallbackMetric) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, led.get, time.Second, 10*time.Millisecond)
+
+	// leader is back: the endpoint renders the epoch again
+	e.Sequence++
+	require.NoError(t, evh.republishEpoch(ctx, e))
+	assert.Eventually(t, func() bool { return testutil.ToFloat64(fallbackMetric) == 0 }, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return !led.get() }, time.Second, 10*time.Millisecond)
+}
+
+func TestEndpoint_Run_Fallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     FallbackMode
+		fallback func(*fakeTriggerLED) bool
+	}{
+		{name: "off", mode: FallbackOff, fallback: func(led *fakeTriggerLED) bool { return !led.get() }},
+		{name: "on", mode: FallbackOn, fallback: func(led *fakeTriggerLED) bool { return led.get() }},
+		{name: "trigger", mode: FallbackTrigger, fallback: func(led *fakeTriggerLED) bool { return led.getTrigger() == "heartbeat" }},
+		{name: "orphaned", mode: FallbackOrphaned, fallback: func(led *fakeTriggerLED) bool { return led.written() >= 4 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			led := fakeTriggerLED{trigger: "heartbeat"}
+			ep := Endpoint{
+				nodeName:     "localhost",
+				eventHandler: &fakeEventHandler{},
+				LED:          &led,
+				logger:       slog.New(slog.DiscardHandler),
+			}
+			ep.SetFallback(Fallback{Mode: tt.mode, Timeout: 100 * time.Millisecond})
+
+			ctx := t.Context()
+			go func() {
+				require.NoError(t, ep.Run(ctx))
+			}()
+			<-ep.Ready()
+
+			// follow the leader
+			led.setTrigger("none")
+			_ = ep.publishLEDStates(ctx, stateUpdate{States: ledStates{"localhost": true}, header: header{Term: 1, Sequence: 1}})
+			assert.Eventually(t, led.get, time.Second, 10*time.Millisecond)
+			assert.Zero(t, testutil.ToFloat64(fallbackMetric))
+
+			// leader goes silent
+			assert.Eventually(t, func() bool { return testutil.ToFloat64(fallbackMetric) == 1 }, time.Second, 10*time.Millisecond)
+			assert.Eventually(t, func() bool { return tt.fallback(&led) }, time.Second, 10*time.Millisecond)
+
+			// leader is back
+			_ = ep.publishLEDStates(ctx, stateUpdate{States: ledStates{"localhost": false}, header: header{Term: 1, Sequence: 2}})
+			_ = ep.publishLEDStates(ctx, stateUpdate{States: ledStates{"localhost": true}, header: header{Term: 1, Sequence: 3}})
+			assert.Eventually(t, func() bool { return testutil.ToFloat64(fallbackMetric) == 0 }, time.Second, 10*time.Millisecond)
+			assert.Eventually(t, led.get, time.Second, 10*time.Millisecond)
+			assert.Equal(t, "none", led.getTrigger())
+		})
+	}
+}
+
+func TestParseFallbackMode(t *testing.T) {
+	for _, name := range []string{"", "none", "off", "on", "trigger", "orphaned"} {
+		_, err := ParseFallbackMode(name)
+		assert.NoError(t, err, name)
+	}
+	mode, _ := ParseFallbackMode("")
+	assert.Equal(t, FallbackNone, mode)
+	_, err := ParseFallbackMode("blink")
+	assert.Error(t, err)
+}
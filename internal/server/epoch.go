@@ -0,0 +1,145 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+)
+
+// Protocol determines how the Leader distributes the pattern to the endpoints.
+type Protocol string
+
+const (
+	// ProtocolStates publishes the state of all LEDs at every rotation.
+	ProtocolStates Protocol = "states"
+	// ProtocolEpochs publishes a description of the schedule (an epoch) whenever it changes, and periodically
+	// republishes it with the current state of the schedule. Each endpoint then computes the state of its own LED
+	// from the shared wall clock, so the nodes' clocks must be synchronized (e.g. using NTP). Schedules that can't be
+	// recreated by the endpoints fall back to ProtocolStates.
+	ProtocolEpochs Protocol = "epochs"
+)
+
+// ParseProtocol returns the Protocol for the specified name. An empty name selects ProtocolStates.
+func ParseProtocol(name string) (Protocol, error) {
+	switch Protocol(name) {
+	case "", ProtocolStates:
+		return ProtocolStates, nil
+	case ProtocolEpochs:
+		return ProtocolEpochs, nil
+	default:
+		return "", fmt.Errorf("invalid protocol: %s", name)
+	}
+}
+
+// epochRefresh is the number of rotations after which the Leader republishes an unchanged epoch, starting from the
+// current state of its schedule. Endpoints that join a long-running epoch then don't have to replay all of its patterns.
+const epochRefresh = 500
+
+// epoch describes everything an endpoint needs to render the pattern locally: the first pattern is shown at Start,
+// and the next one every Interval after that.
+type epoch struct {
+	Start    time.Time           `json:"start"`
+	Schedule schedule.Descriptor `json:"schedule"`
+	Nodes    []string            `json:"nodes"`
+	Columns  int                 `json:"columns,omitempty"`
+	Interval time.Duration       `json:"interval"`
+	// Capabilities are the capabilities of the nodes, in the order of Nodes, for schedule.CapabilityAware schedules.
+	Capabilities []schedule.Capabilities `json:"capabilities,omitempty"`
+	// State is the state of the schedule at Start (see schedule.Stateful). If empty, the pattern starts from the beginning.
+	State []byte `json:"state,omitempty"`
+	header
+}
+
+// sameSchedule reports whether two epochs render the same pattern, regardless of when they start.
+func (e epoch) sameSchedule(other epoch) bool {
+	return e.Schedule.Equal(other.Schedule) &&
+		slices.Equal(e.Nodes, other.Nodes) &&
+		e.Columns == other.Columns &&
+		e.Interval == other.Interval &&
+		slices.EqualFunc(e.Capabilities, other.Capabilities, schedule.Capabilities.Equal)
+}
+
+// renderer computes the state of a node's LED for an epoch.
+type renderer struct {
+	schedule Schedule
+	epoch
+	layout Layout
+	// index is the node's position in the pattern, or -1 if the node isn't part of the pattern
+	index int
+	// ticks is the number of patterns rendered so far
+	ticks int
+}
+
+func newRenderer(e epoch, nodeName string) (*renderer, error) {
+	if e.Interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	if e.Start.IsZero() {
+		return nil, errors.New("start must be set")
+	}
+	if len(e.Capabilities) > 0 && len(e.Capabilities) != len(e.Nodes) {
+		return nil, fmt.Errorf("epoch has capabilities for %d of %d nodes", len(e.Capabilities), len(e.Nodes))
+	}
+	s, err := e.Schedule.New()
+	if err != nil {
+		return nil, err
+	}
+	if len(e.State) > 0 {
+		stateful, ok := s.(schedule.Stateful)
+		if !ok {
+			return nil, fmt.Errorf("schedule %s has no state", e.Schedule.Mode)
+		}
+		if err = stateful.UnmarshalState(e.State); err != nil {
+			return nil, fmt.Errorf("restore state: %w", err)
+		}
+	}
+	return &renderer{
+		epoch:    e,
+		schedule: s,
+		layout:   Layout{Columns: e.Columns},
+		index:    slices.Index(e.Nodes, nodeName),
+	}, nil
+}
+
+// render returns the state of the node's LED at the specified time. If no new pattern is due, ok is false.
+// If patterns were missed, render catches up, so all endpoints see the same sequence of patterns. If more than
+// maxCatchUp patterns were missed, render skips the oldest ones, so the pattern may differ from the other endpoints
+// until the Leader republishes the epoch.
+func (r *renderer) render(now time.Time) (state bool, ok bool) {
+	due := r.due(now)
+	if due <= r.ticks {
+		return false, false
+	}
+	r.ticks = max(r.ticks, due-maxCatchUp)
+	var states []bool
+	for ; r.ticks < due; r.ticks++ {
+		r.layout.setCapabilities(r.schedule, r.Capabilities)
+		states = r.layout.next(r.schedule, len(r.Nodes))
+	}
+	return r.index >= 0 && r.index < len(states) && states[r.index], true
+}
+
+// stateful returns the renderer's schedule, if its state can be saved. Returns false if r is nil.
+func (r *renderer) stateful() (schedule.Stateful, bool) {
+	if r == nil {
+		return nil, false
+	}
+	s, ok := r.schedule.(schedule.Stateful)
+	return s, ok
+}
+
+// due returns the number of patterns due at the specified time.
+func (r *renderer) due(now time.Time) int {
+	if now.Before(r.Start) {
+		return 0
+	}
+	return int(now.Sub(r.Start)/r.Interval) + 1
+}
+
+// next returns the time of the next pattern.
+func (r *renderer) next() time.Time {
+	return r.Start.Add(time.Duration(r.ticks) * r.Interval)
+}
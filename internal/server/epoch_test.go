@@ -0,0 +1,138 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Protocol
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{name: "", want: ProtocolStates, wantErr: assert.NoError},
+		{name: "states", want: ProtocolStates, wantErr: assert.NoError},
+		{name: "epochs", want: ProtocolEpochs, wantErr: assert.NoError},
+		{name: "invalid", wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocol, err := ParseProtocol(tt.name)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.want, protocol)
+		})
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	e := epoch{
+		Schedule: schedule.Descriptor{Mode: "linear"},
+		Nodes:    []string{"node1", "node2", "node3"},
+		Start:    start,
+		Interval: time.Second,
+	}
+
+	r, err := newRenderer(e, "node1")
+	require.NoError(t, err)
+
+	// nothing is due before the start of the epoch
+	_, ok := r.render(start.Add(-time.Millisecond))
+	assert.False(t, ok)
+	assert.Equal(t, start, r.next())
+
+	// linear: node2, node3, node1, node2, ...
+	for i, want := range []bool{false, false, true, false} {
+		state, ok := r.render(start.Add(time.Duration(i) * time.Second))
+		require.True(t, ok)
+		assert.Equal(t, want, state, i)
+	}
+	assert.Equal(t, start.Add(4*time.Second), r.next())
+
+	// nothing new is due until the next tick
+	_, ok = r.render(start.Add(3500 * time.Millisecond))
+	assert.False(t, ok)
+
+	// a late endpoint catches up
+	late, err := newRenderer(e, "node1")
+	require.NoError(t, err)
+	state, ok := late.render(start.Add(5 * time.Second))
+	require.True(t, ok)
+	assert.True(t, state)
+}
+
+func TestRenderer_State(t *testing.T) {
+	s := schedule.LinearSchedule{}
+	_ = s.Next(3)
+	state, err := s.MarshalState()
+	require.NoError(t, err)
+
+	start := time.Now()
+	r, err := newRenderer(epoch{Schedule: schedule.Descriptor{Mode: "linear"}, Nodes: []string{"node1", "node2", "node3"}, Start: start, Interval: time.Second, State: state}, "node1")
+	require.NoError(t, err)
+
+	// the pattern continues from the state: node3, node1, node2
+	for i, want := range []bool{false, true, false} {
+		got, ok := r.render(start.Add(time.Duration(i) * time.Second))
+		require.True(t, ok)
+		assert.Equal(t, want, got, i)
+	}
+}
+
+func TestRenderer_CatchUp(t *testing.T) {
+	start := time.Now()
+	r, err := newRenderer(epoch{Schedule: schedule.Descriptor{Mode: "linear"}, Nodes: []string{"node1"}, Start: start, Interval: time.Millisecond}, "node1")
+	require.NoError(t, err)
+
+	// an endpoint that joins a long-running epoch doesn't replay all of its patterns
+	_, ok := r.render(start.Add(24 * time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, int(24*time.Hour/time.Millisecond)+1, r.ticks)
+}
+
+func TestRenderer_NotInPattern(t *testing.T) {
+	r, err := newRenderer(epoch{Schedule: schedule.Descriptor{Mode: "linear"}, Nodes: []string{"node1"}, Start: time.Now(), Interval: time.Second}, "node2")
+	require.NoError(t, err)
+	state, ok := r.render(time.Now())
+	assert.True(t, ok)
+	assert.False(t, state)
+}
+
+func TestRenderer_Capabilities(t *testing.T) {
+	var s capabilityAwareSchedule
+	capabilities := []schedule.Capabilities{{LEDs: 1, MaxBrightness: 255}, {LEDs: 1, MaxBrightness: 1}}
+	start := time.Now()
+	r := renderer{
+		schedule: &s,
+		epoch:    epoch{Nodes: []string{"node1", "node2"}, Capabilities: capabilities, Start: start, Interval: time.Second},
+		index:    0,
+	}
+	_, ok := r.render(start)
+	require.True(t, ok)
+	assert.Equal(t, capabilities, s.capabilities)
+}
+
+func TestRenderer_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		epoch epoch
+	}{
+		{name: "no interval", epoch: epoch{Schedule: schedule.Descriptor{Mode: "linear"}, Start: time.Now()}},
+		{name: "no start", epoch: epoch{Schedule: schedule.Descriptor{Mode: "linear"}, Interval: time.Second}},
+		{name: "invalid schedule", epoch: epoch{Schedule: schedule.Descriptor{Mode: "invalid"}, Start: time.Now(), Interval: time.Second}},
+		{name: "no state", epoch: epoch{Schedule: schedule.Descriptor{Mode: "sparkle"}, Start: time.Now(), Interval: time.Second, State: []byte(`{}`)}},
+		{name: "invalid state", epoch: epoch{Schedule: schedule.Descriptor{Mode: "linear"}, Start: time.Now(), Interval: time.Second, State: []byte(`invalid`)}},
+		{name: "missing capabilities", epoch: epoch{Schedule: schedule.Descriptor{Mode: "linear"}, Nodes: []string{"node1", "node2"}, Capabilities: []schedule.Capabilities{{LEDs: 1}}, Start: time.Now(), Interval: time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRenderer(tt.epoch, "node1")
+			assert.Error(t, err)
+		})
+	}
+}
@@ -3,19 +3,42 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"maps"
 	"slices"
 	"sort"
+	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redis/go-redis/v9"
 )
 
+// Names of the channels and keys, within a group. See redisEventHandler.name for the full name.
 const (
-	channelLED  = "ledswitcher.led"
-	channelNode = "ledswitcher.node"
+	channelLED = "led"
+	// channelNode carries the bare names of registering nodes, as the first versions did.
+	channelNode = "node"
+	// channelRegistration carries the full registrations: the node's NodeInfo, or the announcement that it's leaving.
+	channelRegistration = "registration"
+	channelMessage      = "message"
+	channelEpoch        = "epoch"
+
+	// keyEpoch holds the current epoch, so endpoints that start after it was published can pick it up.
+	keyEpoch = "epoch"
+	// keyTerm holds the last leadership term.
+	keyTerm = "term"
+	// keyRegistry holds the snapshot of the leader's registry.
+	keyRegistry = "registry"
+	// keyCheckpoint holds the last checkpoint of the leader's schedule.
+	keyCheckpoint = "checkpoint"
+	// keyNodeTable holds the current node table, so endpoints can decode frames published before they started.
+	keyNodeTable = "nodes"
+
+	// namePrefix is the prefix of all channel and key names.
+	namePrefix = "ledswitcher"
 )
 
 var (
@@ -34,17 +57,100 @@ var (
 		Help:        "Number of events received",
 		ConstLabels: nil,
 	}, []string{"channel"})
+
+	rejectedEventsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ledswitcher",
+		Subsystem: "events",
+		Name:      "rejected_total",
+		Help:      "Number of events rejected because they were not signed, had an invalid signature, or were signed too long ago",
+	}, []string{"channel", "reason"})
+
+	unverifiedEventsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ledswitcher",
+		Subsystem: "events",
+		Name:      "unverified_total",
+		Help:      "Number of signed events accepted without checking the signature, because the node has no signing key",
+	}, []string{"channel"})
 )
 
 type eventHandler interface {
-	publishLEDStates(ctx context.Context, states ledStates) error
-	ledStates(ctx context.Context, logger *slog.Logger) <-chan ledStates
-	publishNode(ctx context.Context, info string) error
-	nodes(ctx context.Context, logger *slog.Logger) <-chan node
+	publishLEDStates(ctx context.Context, update stateUpdate) error
+	publishNodeTable(ctx context.Context, t nodeTable) error
+	publishFrame(ctx context.Context, f frame) error
+	ledStates(ctx context.Context, logger *slog.Logger) (<-chan stateUpdate, error)
+	publishNode(ctx context.Context, info node) error
+	nodes(ctx context.Context, logger *slog.Logger) (<-chan node, error)
+	publishMessage(ctx context.Context, message string) error
+	messages(ctx context.Context, logger *slog.Logger) (<-chan string, error)
+	publishEpoch(ctx context.Context, e epoch) error
+	republishEpoch(ctx context.Context, e epoch) error
+	epochs(ctx context.Context, logger *slog.Logger) (<-chan epoch, error)
+	currentEpoch(ctx context.Context) (epoch, bool, error)
+	nextTerm(ctx context.Context) (uint64, error)
+	saveRegistry(ctx context.Context, registrations []registration, ttl time.Duration) error
+	loadRegistry(ctx context.Context) ([]registration, error)
+	storeCheckpoint(ctx context.Context, c checkpoint, ttl time.Duration) error
+	loadCheckpoint(ctx context.Context) (checkpoint, bool, error)
 	ping(ctx context.Context) error
 }
 
-type node string
+// node is the message a node publishes to register itself, or to announce that it is leaving.
+type node struct {
+	NodeInfo
+	Name    string `json:"name"`
+	Leaving bool   `json:"leaving,omitempty"`
+	// nameOnly is set for registrations that only carry the node's name. See channelNode.
+	nameOnly bool
+}
+
+// UnmarshalJSON also accepts the bare node name sent on channelNode.
+func (n *node) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*n = node{nameOnly: true}
+		return json.Unmarshal(data, &n.Name)
+	}
+	type plain node
+	return json.Unmarshal(data, (*plain)(n))
+}
+
+// header identifies the leader that published a message and the message's place in the leader's sequence, so endpoints
+// can reject messages from a previous leader, or messages that arrive out of order.
+type header struct {
+	Leader string `json:"leader,omitempty"`
+	// Term is the leader's leadership term. Each new leader gets a higher term. If zero, the message isn't checked.
+	Term uint64 `json:"term,omitempty"`
+	// Sequence increases with every message the leader publishes during its term.
+	Sequence uint64 `json:"seq,omitempty"`
+}
+
+// stateUpdate is the message the leader publishes with the LED states of all nodes.
+type stateUpdate struct {
+	// ApplyAt is the time at which the endpoints should apply the states. If zero, states are applied on arrival.
+	ApplyAt time.Time `json:"applyAt,omitzero"`
+	States  ledStates `json:"states"`
+	header
+}
+
+// MarshalJSON sends updates without ApplyAt or header as bare LED states, i.e. in EncodingJSON. The leader only sets
+// ApplyAt and the header if all nodes advertise EncodingEnvelope.
+func (u stateUpdate) MarshalJSON() ([]byte, error) {
+	if u.ApplyAt.IsZero() && u.header == (header{}) {
+		return json.Marshal(u.States)
+	}
+	type plain stateUpdate
+	return json.Marshal(plain(u))
+}
+
+// UnmarshalJSON also accepts the bare LED states sent by older versions.
+func (u *stateUpdate) UnmarshalJSON(data []byte) error {
+	var states ledStates
+	if err := json.Unmarshal(data, &states); err == nil {
+		*u = stateUpdate{States: states}
+		return nil
+	}
+	type plain stateUpdate
+	return json.Unmarshal(data, (*plain)(u))
+}
 
 var _ slog.LogValuer = ledStates{}
 
@@ -64,23 +170,246 @@ func (l ledStates) LogValue() slog.Value {
 var _ eventHandler = &redisEventHandler{}
 
 type redisEventHandler struct {
-	*redis.Client
+	redis.UniversalClient
+	signer atomic.Pointer[Signer]
+	// group isolates the channels and keys of one group of nodes from other groups sharing the same Redis.
+	group string
+}
+
+// name returns the full name of a channel or key: ledswitcher.<name>, or ledswitcher.<group>.<name> if a group is set.
+func (r *redisEventHandler) name(name string) string {
+	if r.group == "" {
+		return namePrefix + "." + name
+	}
+	return namePrefix + "." + r.group + "." + name
+}
+
+// seal signs the payload, if a Signer is set.
+func (r *redisEventHandler) seal(channel string, payload []byte) []byte {
+	if signer := r.signer.Load(); signer != nil {
+		return signer.sign(channel, payload)
+	}
+	return payload
+}
+
+// open returns the payload of a received message or a stored value. If a Signer is set, the message must be signed with
+// an accepted key, no more than maxAge ago (see Signer.verify). Stored values pass a maxAge of zero, as they can be read
+// long after they were written; their signature still covers the key. Otherwise, the signature is removed without being
+// checked, so nodes without a key can still read signed messages. These are counted, so a node that is missing its key
+// shows up in the metrics.
+func (r *redisEventHandler) open(name string, msg []byte, maxAge time.Duration) ([]byte, error) {
+	signer := r.signer.Load()
+	if signer == nil {
+		_, _, payload, signed := unwrap(msg)
+		if signed {
+			unverifiedEventsMetric.WithLabelValues(name).Inc()
+		}
+		return payload, nil
+	}
+	return signer.verify(name, msg, maxAge)
+}
+
+func (r *redisEventHandler) publishLEDStates(ctx context.Context, update stateUpdate) error {
+	return r.publish(ctx, r.name(channelLED), update)
+}
+
+func (r *redisEventHandler) publishNodeTable(ctx context.Context, t nodeTable) error {
+	payload, err := t.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("marshal node table: %w", err)
+	}
+	key := r.name(keyNodeTable)
+	if err := r.UniversalClient.Set(ctx, key, r.seal(key, payload), 0).Err(); err != nil {
+		return fmt.Errorf("store node table: %w", err)
+	}
+	return r.publishPayload(ctx, r.name(channelLED), payload)
+}
+
+func (r *redisEventHandler) publishFrame(ctx context.Context, f frame) error {
+	payload, err := f.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("marshal frame: %w", err)
+	}
+	return r.publishPayload(ctx, r.name(channelLED), payload)
+}
+
+// ledStates returns the LED states published by the leader, in any encoding. The current node table is loaded once the
+// subscription is live, so frames that reference a table published before the subscription can be decoded. Later
+// tables are received on the channel.
+func (r *redisEventHandler) ledStates(ctx context.Context, logger *slog.Logger) (<-chan stateUpdate, error) {
+	channel := r.name(channelLED)
+	sub, err := r.listen(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	var decoder ledDecoder
+	if decoder.table, _, err = r.currentNodeTable(ctx); err != nil {
+		_ = sub.Close()
+		return nil, fmt.Errorf("node table: %w", err)
+	}
+	return receive(r, sub, logger, decoder.decode), nil
+}
+
+func (r *redisEventHandler) currentNodeTable(ctx context.Context) (nodeTable, bool, error) {
+	key := r.name(keyNodeTable)
+	payload, err := r.UniversalClient.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nodeTable{}, false, nil
+	}
+	if err != nil {
+		return nodeTable{}, false, err
+	}
+	if payload, err = r.open(key, payload, 0); err != nil {
+		return nodeTable{}, false, err
+	}
+	var t nodeTable
+	if err = t.UnmarshalBinary(payload); err != nil {
+		return nodeTable{}, false, err
+	}
+	return t, true, nil
+}
+
+// publishNode publishes the registration on channelRegistration. Registering nodes also publish their bare name on
+// channelNode, so versions that only read channelNode keep seeing them while a cluster is upgraded. Those versions don't
+// see nodes leave: they wait for them to expire.
+func (r *redisEventHandler) publishNode(ctx context.Context, info node) error {
+	if !info.Leaving {
+		if err := r.publish(ctx, r.name(channelNode), info.Name); err != nil {
+			return err
+		}
+	}
+	return r.publish(ctx, r.name(channelRegistration), info)
+}
+
+// nodes returns the registrations on both channelNode and channelRegistration. Both channels share one subscription,
+// so a node's registrations arrive in the order they were published.
+func (r *redisEventHandler) nodes(ctx context.Context, logger *slog.Logger) (<-chan node, error) {
+	return subscribe[node](ctx, r, logger, r.name(channelNode), r.name(channelRegistration))
 }
 
-func (r *redisEventHandler) publishLEDStates(ctx context.Context, states ledStates) error {
-	return r.publish(ctx, channelLED, states)
+func (r *redisEventHandler) publishMessage(ctx context.Context, message string) error {
+	return r.publish(ctx, r.name(channelMessage), message)
 }
 
-func (r *redisEventHandler) ledStates(ctx context.Context, logger *slog.Logger) <-chan ledStates {
-	return subscribe[ledStates](ctx, r.Client, channelLED, logger)
+func (r *redisEventHandler) messages(ctx context.Context, logger *slog.Logger) (<-chan string, error) {
+	return subscribe[string](ctx, r, logger, r.name(channelMessage))
 }
 
-func (r *redisEventHandler) publishNode(ctx context.Context, info string) error {
-	return r.publish(ctx, channelNode, info)
+func (r *redisEventHandler) publishEpoch(ctx context.Context, e epoch) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+	key := r.name(keyEpoch)
+	if err = r.UniversalClient.Set(ctx, key, r.seal(key, payload), 0).Err(); err != nil {
+		return fmt.Errorf("store epoch: %w", err)
+	}
+	return r.publish(ctx, r.name(channelEpoch), e)
+}
+
+// republishEpoch publishes the current epoch again, without storing it, so the endpoints know the leader is alive.
+func (r *redisEventHandler) republishEpoch(ctx context.Context, e epoch) error {
+	return r.publish(ctx, r.name(channelEpoch), e)
+}
+
+func (r *redisEventHandler) epochs(ctx context.Context, logger *slog.Logger) (<-chan epoch, error) {
+	return subscribe[epoch](ctx, r, logger, r.name(channelEpoch))
+}
+
+func (r *redisEventHandler) currentEpoch(ctx context.Context) (epoch, bool, error) {
+	key := r.name(keyEpoch)
+	payload, err := r.UniversalClient.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return epoch{}, false, nil
+	}
+	if err != nil {
+		return epoch{}, false, err
+	}
+	if payload, err = r.open(key, payload, 0); err != nil {
+		return epoch{}, false, err
+	}
+	var e epoch
+	if err = json.Unmarshal(payload, &e); err != nil {
+		return epoch{}, false, fmt.Errorf("json unmarshal: %w", err)
+	}
+	return e, true, nil
+}
+
+// nextTermScript increments the term. The term is at least the current time in milliseconds, so terms keep increasing
+// even if Redis loses the stored term.
+var nextTermScript = redis.NewScript(`
+local term = math.max((tonumber(redis.call('GET', KEYS[1])) or 0) + 1, tonumber(ARGV[1]))
+redis.call('SET', KEYS[1], term)
+return term
+`)
+
+func (r *redisEventHandler) nextTerm(ctx context.Context) (uint64, error) {
+	term, err := nextTermScript.Run(ctx, r.UniversalClient, []string{r.name(keyTerm)}, time.Now().UnixMilli()).Uint64()
+	if err != nil {
+		return 0, fmt.Errorf("next term: %w", err)
+	}
+	return term, nil
+}
+
+// saveRegistry stores a snapshot of the registry. The snapshot is removed after ttl, unless it is saved again.
+func (r *redisEventHandler) saveRegistry(ctx context.Context, registrations []registration, ttl time.Duration) error {
+	payload, err := json.Marshal(registrations)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+	key := r.name(keyRegistry)
+	return r.UniversalClient.Set(ctx, key, r.seal(key, payload), ttl).Err()
+}
+
+// loadRegistry returns the stored snapshot of the registry. If no snapshot exists, it returns no registrations.
+func (r *redisEventHandler) loadRegistry(ctx context.Context) ([]registration, error) {
+	key := r.name(keyRegistry)
+	payload, err := r.UniversalClient.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if payload, err = r.open(key, payload, 0); err != nil {
+		return nil, err
+	}
+	var registrations []registration
+	if err = json.Unmarshal(payload, &registrations); err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+	return registrations, nil
 }
 
-func (r *redisEventHandler) nodes(ctx context.Context, logger *slog.Logger) <-chan node {
-	return subscribe[node](ctx, r.Client, channelNode, logger)
+// storeCheckpoint stores the checkpoint of the leader's schedule. The checkpoint is removed after ttl, unless it is
+// stored again.
+func (r *redisEventHandler) storeCheckpoint(ctx context.Context, c checkpoint, ttl time.Duration) error {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+	key := r.name(keyCheckpoint)
+	return r.UniversalClient.Set(ctx, key, r.seal(key, payload), ttl).Err()
+}
+
+// loadCheckpoint returns the stored checkpoint of the leader's schedule.
+func (r *redisEventHandler) loadCheckpoint(ctx context.Context) (checkpoint, bool, error) {
+	key := r.name(keyCheckpoint)
+	payload, err := r.UniversalClient.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return checkpoint{}, false, nil
+	}
+	if err != nil {
+		return checkpoint{}, false, err
+	}
+	if payload, err = r.open(key, payload, 0); err != nil {
+		return checkpoint{}, false, err
+	}
+	var c checkpoint
+	if err = json.Unmarshal(payload, &c); err != nil {
+		return checkpoint{}, false, fmt.Errorf("json unmarshal: %w", err)
+	}
+	return c, true, nil
 }
 
 func (r *redisEventHandler) publish(ctx context.Context, channel string, msg any) error {
@@ -88,7 +417,12 @@ func (r *redisEventHandler) publish(ctx context.Context, channel string, msg any
 	if err != nil {
 		return fmt.Errorf("json marshal: %w", err)
 	}
-	if err = r.Client.Publish(ctx, channel, payload).Err(); err == nil {
+	return r.publishPayload(ctx, channel, payload)
+}
+
+func (r *redisEventHandler) publishPayload(ctx context.Context, channel string, payload []byte) error {
+	err := r.UniversalClient.Publish(ctx, channel, r.seal(channel, payload)).Err()
+	if err == nil {
 		//goland:noinspection GoMaybeNil
 		publishedEventsMetric.WithLabelValues(channel).Inc()
 	}
@@ -96,24 +430,77 @@ func (r *redisEventHandler) publish(ctx context.Context, channel string, msg any
 }
 
 func (r *redisEventHandler) ping(ctx context.Context) error {
-	return r.Client.Ping(ctx).Err()
+	return r.UniversalClient.Ping(ctx).Err()
 }
 
-func subscribe[T any](ctx context.Context, c *redis.Client, channel string, logger *slog.Logger) <-chan T {
-	sub := c.Subscribe(ctx, channel)
+// subscribe subscribes to one or more channels and returns the decoded messages. It only returns once the broker has
+// confirmed the subscription, so any message published after subscribe returns is guaranteed to be received.
+func subscribe[T any](ctx context.Context, r *redisEventHandler, logger *slog.Logger, channels ...string) (<-chan T, error) {
+	sub, err := r.listen(ctx, channels...)
+	if err != nil {
+		return nil, err
+	}
+	return receive(r, sub, logger, func(payload []byte) (T, bool, error) {
+		var t T
+		err := json.Unmarshal(payload, &t)
+		return t, err == nil, err
+	}), nil
+}
+
+// listen subscribes to one or more channels. It only returns once the broker has confirmed all subscriptions.
+func (r *redisEventHandler) listen(ctx context.Context, channels ...string) (*redis.PubSub, error) {
+	sub := r.UniversalClient.Subscribe(ctx, channels...)
+	for _, channel := range channels {
+		if _, err := sub.Receive(ctx); err != nil {
+			_ = sub.Close()
+			return nil, fmt.Errorf("subscribe %s: %w", channel, err)
+		}
+	}
+	return sub, nil
+}
+
+// receive returns the messages of a subscription, decoded by decode. Messages that fail verification
+// (see redisEventHandler.open), or for which decode returns ok == false, are not returned. decode must not block,
+// as it runs in the loop that receives the messages.
+func receive[T any](
+	r *redisEventHandler,
+	sub *redis.PubSub,
+	logger *slog.Logger,
+	decode func([]byte) (T, bool, error),
+) <-chan T {
 	in := sub.Channel()
 	out := make(chan T)
 	go func() {
 		defer close(out)
 		for msg := range in {
-			var t T
-			if err := json.Unmarshal([]byte(msg.Payload), &t); err != nil {
-				logger.Warn("json unmarshal", "channel", channel, "err", err)
+			channel := msg.Channel
+			payload, err := r.open(channel, []byte(msg.Payload), signatureWindow)
+			if err != nil {
+				rejectedEventsMetric.WithLabelValues(channel, rejectReason(err)).Inc()
+				logger.Warn("message rejected", "channel", channel, "err", err)
+				continue
+			}
+			t, ok, err := decode(payload)
+			if err != nil {
+				logger.Warn("failed to decode message", "channel", channel, "err", err)
 				continue
 			}
-			out <- t
 			receivedEventsMetrics.WithLabelValues(channel).Inc()
+			if ok {
+				out <- t
+			}
 		}
 	}()
 	return out
 }
+
+func rejectReason(err error) string {
+	switch {
+	case errors.Is(err, errUnsigned):
+		return "unsigned"
+	case errors.Is(err, errExpired):
+		return "expired"
+	default:
+		return "invalid_signature"
+	}
+}
@@ -2,12 +2,14 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 	"os"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/clambin/ledswitcher/internal/schedule"
 	"github.com/clambin/ledswitcher/internal/testutils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -17,18 +19,33 @@ func TestRedisEventHandler_Nodes(t *testing.T) {
 	container, client, err := testutils.StartRedis(t.Context())
 	require.NoError(t, err)
 	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
-	handler := &redisEventHandler{Client: client}
+	handler := &redisEventHandler{UniversalClient: client}
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	want := []node{"node1", "node2", "node3", "node4"}
+	published := []node{{Name: "node1"}, {Name: "node2", NodeInfo: NodeInfo{Version: "v1"}}, {Name: "node3", Leaving: true}}
+	// registrations are published as a bare name, for older versions, and as a full registration
+	want := []node{
+		{Name: "node1", nameOnly: true}, {Name: "node1"},
+		{Name: "node2", nameOnly: true}, {Name: "node2", NodeInfo: NodeInfo{Version: "v1"}},
+		{Name: "node3", Leaving: true},
+	}
 	received := make([]node, 0, len(want))
 
+	ch, err := handler.nodes(t.Context(), logger)
+	require.NoError(t, err)
+
+	// the first versions subscribe to channelNode and decode the node's name as a string
+	sub := client.Subscribe(t.Context(), "ledswitcher.node")
+	_, err = sub.Receive(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sub.Close() })
+
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		var count int
-		for node := range handler.nodes(t.Context(), logger) {
+		for node := range ch {
 			received = append(received, node)
 			count++
 			if count == len(want) {
@@ -37,37 +54,45 @@ func TestRedisEventHandler_Nodes(t *testing.T) {
 		}
 	}()
 
-	// TODO: race condition! can only publish when someone's listening
-	time.Sleep(time.Second)
-
-	for _, node := range want {
-		require.NoError(t, handler.publishNode(t.Context(), string(node)))
+	for _, node := range published {
+		require.NoError(t, handler.publishNode(t.Context(), node))
 	}
 	wg.Wait()
 	assert.Equal(t, want, received)
+
+	for _, name := range []string{"node1", "node2"} {
+		msg := <-sub.Channel()
+		var got string
+		require.NoError(t, json.Unmarshal([]byte(msg.Payload), &got))
+		assert.Equal(t, name, got)
+	}
 }
 
 func TestRedisEventHandler_LEDStates(t *testing.T) {
 	container, client, err := testutils.StartRedis(t.Context())
 	require.NoError(t, err)
 	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
-	handler := &redisEventHandler{Client: client}
+	handler := &redisEventHandler{UniversalClient: client}
 
 	logger := slog.New(slog.DiscardHandler) //slog.NewTextHandler(os.Stdout, nil))
-	want := []ledStates{
-		{"node1": true, "node2": true, "node3": true},
-		{"node1": false, "node2": false, "node3": false},
-		{"node1": true, "node2": true, "node3": true},
-		{"node1": false, "node2": false, "node3": false},
+	applyAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	want := []stateUpdate{
+		{States: ledStates{"node1": true, "node2": true, "node3": true}},
+		{States: ledStates{"node1": false, "node2": false, "node3": false}},
+		{States: ledStates{"node1": true, "node2": true, "node3": true}, ApplyAt: applyAt},
+		{States: ledStates{"node1": false, "node2": false, "node3": false}, ApplyAt: applyAt},
 	}
-	received := make([]ledStates, 0, len(want))
+	received := make([]stateUpdate, 0, len(want))
+
+	ch, err := handler.ledStates(t.Context(), logger)
+	require.NoError(t, err)
 
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		var count int
-		for update := range handler.ledStates(t.Context(), logger) {
+		for update := range ch {
 			received = append(received, update)
 			count++
 			if count == len(want) {
@@ -76,9 +101,6 @@ func TestRedisEventHandler_LEDStates(t *testing.T) {
 		}
 	}()
 
-	// TODO: race condition! can only publish when someone's listening
-	time.Sleep(time.Second)
-
 	for _, update := range want {
 		require.NoError(t, handler.publishLEDStates(t.Context(), update))
 	}
@@ -87,6 +109,165 @@ func TestRedisEventHandler_LEDStates(t *testing.T) {
 	assert.Equal(t, want, received)
 }
 
+func TestRedisEventHandler_Frames(t *testing.T) {
+	container, client, err := testutils.StartRedis(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
+	handler := &redisEventHandler{UniversalClient: client}
+
+	ch, err := handler.ledStates(t.Context(), slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+
+	table := nodeTable{Leader: "node1", Nodes: []string{"node1", "node2"}, Term: 1, ID: 1}
+	require.NoError(t, handler.publishNodeTable(t.Context(), table))
+	require.NoError(t, handler.publishFrame(t.Context(), frame{States: []bool{true, false}, Term: 1, Sequence: 1, TableID: 1}))
+	require.NoError(t, handler.publishLEDStates(t.Context(), stateUpdate{States: ledStates{"node1": false, "node2": true}}))
+
+	assert.Equal(t, stateUpdate{States: ledStates{"node1": true, "node2": false}, header: header{Leader: "node1", Term: 1, Sequence: 1}}, <-ch)
+	assert.Equal(t, stateUpdate{States: ledStates{"node1": false, "node2": true}}, <-ch)
+
+	// a subscriber that missed the node table loads it from redis
+	ch, err = handler.ledStates(t.Context(), slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	require.NoError(t, handler.publishFrame(t.Context(), frame{States: []bool{false, true}, Term: 1, Sequence: 2, TableID: 1}))
+	assert.Equal(t, stateUpdate{States: ledStates{"node1": false, "node2": true}, header: header{Leader: "node1", Term: 1, Sequence: 2}}, <-ch)
+}
+
+func TestRedisEventHandler_BaselineSubscriber(t *testing.T) {
+	container, client, err := testutils.StartRedis(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
+	handler := &redisEventHandler{UniversalClient: client}
+
+	// the first versions subscribe to the channel and decode LED states as a map[string]bool
+	sub := client.Subscribe(t.Context(), "ledswitcher.led")
+	_, err = sub.Receive(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sub.Close() })
+
+	want := map[string]bool{"node1": true, "node2": false}
+	require.NoError(t, handler.publishLEDStates(t.Context(), stateUpdate{States: want}))
+	msg := <-sub.Channel()
+	var got map[string]bool
+	require.NoError(t, json.Unmarshal([]byte(msg.Payload), &got))
+	assert.Equal(t, want, got)
+}
+
+func TestRedisEventHandler_Messages(t *testing.T) {
+	container, client, err := testutils.StartRedis(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
+	handler := &redisEventHandler{UniversalClient: client}
+
+	ch, err := handler.messages(t.Context(), slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+
+	require.NoError(t, handler.publishMessage(t.Context(), "SOS"))
+	assert.Equal(t, "SOS", <-ch)
+}
+
+func TestRedisEventHandler_Epochs(t *testing.T) {
+	container, client, err := testutils.StartRedis(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
+	handler := &redisEventHandler{UniversalClient: client}
+
+	_, ok, err := handler.currentEpoch(t.Context())
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ch, err := handler.epochs(t.Context(), slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+
+	e := epoch{
+		Schedule: schedule.Descriptor{Mode: "linear"},
+		Nodes:    []string{"node1", "node2"},
+		Start:    time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+		Interval: time.Second,
+	}
+	require.NoError(t, handler.publishEpoch(t.Context(), e))
+	assert.Equal(t, e, <-ch)
+
+	current, ok, err := handler.currentEpoch(t.Context())
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, e, current)
+}
+
+func TestRedisEventHandler_NextTerm(t *testing.T) {
+	container, client, err := testutils.StartRedis(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
+	handler := &redisEventHandler{UniversalClient: client}
+
+	first, err := handler.nextTerm(t.Context())
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, first, uint64(time.Now().Add(-time.Minute).UnixMilli()))
+	second, err := handler.nextTerm(t.Context())
+	require.NoError(t, err)
+	assert.Greater(t, second, first)
+
+	// terms keep increasing if redis loses the term
+	require.NoError(t, client.Del(t.Context(), handler.name(keyTerm)).Err())
+	time.Sleep(10 * time.Millisecond)
+	third, err := handler.nextTerm(t.Context())
+	require.NoError(t, err)
+	assert.Greater(t, third, second)
+}
+
+func TestStateUpdate_JSON(t *testing.T) {
+	applyAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		update stateUpdate
+		want   string
+	}{
+		{name: "immediate", update: stateUpdate{States: ledStates{"node1": true}}, want: `{"node1":true}`},
+		{name: "timestamped", update: stateUpdate{States: ledStates{"node1": true}, ApplyAt: applyAt}, want: `{"applyAt":"2024-03-01T12:00:00Z","states":{"node1":true}}`},
+		{
+			name:   "fenced",
+			update: stateUpdate{States: ledStates{"node1": true}, header: header{Leader: "node1", Term: 2, Sequence: 3}},
+			want:   `{"states":{"node1":true},"leader":"node1","term":2,"seq":3}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := json.Marshal(tt.update)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(payload))
+
+			var update stateUpdate
+			require.NoError(t, json.Unmarshal(payload, &update))
+			assert.Equal(t, tt.update, update)
+		})
+	}
+
+	var update stateUpdate
+	assert.Error(t, json.Unmarshal([]byte(`1`), &update))
+}
+
+func TestNode_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    node
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{name: "registration", input: `{"name":"node1"}`, want: node{Name: "node1"}, wantErr: assert.NoError},
+		{name: "leaving", input: `{"name":"node1","leaving":true}`, want: node{Name: "node1", Leaving: true}, wantErr: assert.NoError},
+		{name: "name only", input: `"node1"`, want: node{Name: "node1", nameOnly: true}, wantErr: assert.NoError},
+		{name: "invalid", input: `1`, wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var n node
+			err := json.Unmarshal([]byte(tt.input), &n)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.want, n)
+		})
+	}
+}
+
 func TestLedStates_LogValue(t *testing.T) {
 	l := ledStates{
 		"node1": true,
@@ -95,3 +276,10 @@ func TestLedStates_LogValue(t *testing.T) {
 	}
 	assert.Equal(t, "101", l.LogValue().String())
 }
+
+func TestRedisEventHandler_name(t *testing.T) {
+	var r redisEventHandler
+	assert.Equal(t, "ledswitcher.led", r.name(channelLED))
+	r.group = "rack-a"
+	assert.Equal(t, "ledswitcher.rack-a.led", r.name(channelLED))
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var fallbackMetric = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: "ledswitcher",
+	Subsystem: "endpoint",
+	Name:      "fallback",
+	Help:      "Set to 1 when the endpoint no longer receives LED states from the leader and shows its fallback behaviour",
+})
+
+// FallbackMode determines what an Endpoint shows when it no longer receives LED states from the leader.
+type FallbackMode string
+
+const (
+	// FallbackNone keeps the LED in its last state.
+	FallbackNone FallbackMode = "none"
+	// FallbackOff switches the LED off.
+	FallbackOff FallbackMode = "off"
+	// FallbackOn switches the LED on.
+	FallbackOn FallbackMode = "on"
+	// FallbackTrigger hands the LED back to a kernel trigger.
+	FallbackTrigger FallbackMode = "trigger"
+	// FallbackOrphaned blinks the LED in a pattern that the leader never shows: a short double blink every two seconds.
+	FallbackOrphaned FallbackMode = "orphaned"
+)
+
+// ParseFallbackMode returns the FallbackMode for the provided name. An empty name selects FallbackNone.
+func ParseFallbackMode(name string) (FallbackMode, error) {
+	switch mode := FallbackMode(cmp.Or(name, string(FallbackNone))); mode {
+	case FallbackNone, FallbackOff, FallbackOn, FallbackTrigger, FallbackOrphaned:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid fallback mode: %s", name)
+	}
+}
+
+// Fallback configures what an Endpoint shows when the leader goes silent.
+type Fallback struct {
+	Mode FallbackMode
+	// Trigger is the kernel trigger used by FallbackTrigger. If empty, the trigger that was active when the Endpoint
+	// started is restored.
+	Trigger string
+	// Timeout is the time without LED states or epochs from the leader after which the Endpoint falls back. If zero,
+	// the Endpoint never falls back.
+	Timeout time.Duration
+}
+
+// enabled returns true if the Endpoint should fall back when the leader goes silent.
+func (f Fallback) enabled() bool {
+	return f.Mode != "" && f.Mode != FallbackNone && f.Timeout > 0
+}
+
+// TriggerLED is an LED that can be handed back to a kernel trigger. FallbackTrigger requires the Endpoint's LED
+// to implement TriggerLED.
+type TriggerLED interface {
+	LED
+	GetActiveMode() (string, error)
+	SetActiveMode(string) error
+}
+
+// orphanedBlink is the pattern shown by FallbackOrphaned.
+var orphanedBlink = []struct {
+	duration time.Duration
+	state    bool
+}{
+	{state: true, duration: 100 * time.Millisecond},
+	{state: false, duration: 150 * time.Millisecond},
+	{state: true, duration: 100 * time.Millisecond},
+	{state: false, duration: 1650 * time.Millisecond},
+}
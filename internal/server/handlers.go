@@ -1,6 +1,12 @@
 package server
 
-import "net/http"
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
 
 func HealthHandler(s *Server) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -12,3 +18,54 @@ func HealthHandler(s *Server) http.Handler {
 		w.WriteHeader(http.StatusOK)
 	})
 }
+
+// StatusHandler reports the Server's status, including the registered nodes, as JSON.
+func StatusHandler(s *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s.Status()); err != nil {
+			s.Endpoint.logger.Warn("failed to encode status", "err", err)
+		}
+	})
+}
+
+// maxMessageSize is the maximum size of a message accepted by MessageHandler.
+const maxMessageSize = 1024
+
+// MessageHandler sets the message shown by schedules that display a text message (e.g. morse, marquee).
+// The message, sent as the request body, is broadcast to all nodes, so it reaches the current and any future leader.
+//
+// Requests must carry the token as a bearer token ("Authorization: Bearer <token>"). The message is signed with
+// the node's key, like all messages the node publishes, so the token is what keeps others from changing the display.
+// If the token is empty, all requests are rejected.
+func MessageHandler(s *Server, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			w.Header().Set("Allow", "POST, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		message, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+		if err != nil {
+			http.Error(w, "invalid message: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = s.Leader.publishMessage(r.Context(), string(message)); err != nil {
+			s.Leader.logger.Warn("failed to publish message", "err", err)
+			http.Error(w, "redis: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// authorized returns true if the request carries the token as a bearer token.
+func authorized(r *http.Request, token string) bool {
+	bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1
+}
@@ -2,16 +2,19 @@ package server
 
 import (
 	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestHealthHandler(t *testing.T) {
-	srv := NewServer("localhost", nil, nil, nil, 0, 0, 0, nil, slog.New(slog.DiscardHandler))
+	srv := NewServer("localhost", NodeInfo{}, nil, nil, nil, 0, 0, 0, 0, nil, slog.New(slog.DiscardHandler))
 	var evh fakeEventHandler
 	srv.Endpoint.eventHandler = &evh
 
@@ -27,3 +30,64 @@ func TestHealthHandler(t *testing.T) {
 	h.ServeHTTP(w, req)
 	require.Equal(t, http.StatusServiceUnavailable, w.Code)
 }
+
+func TestStatusHandler(t *testing.T) {
+	srv := NewServer("localhost", NodeInfo{}, nil, nil, nil, 0, 0, 0, 0, nil, slog.New(slog.DiscardHandler))
+	srv.SetLeader("localhost")
+	srv.SetGroup("rack-a")
+	require.NoError(t, srv.Registry.registerNode(node{Name: "node2"}))
+	require.NoError(t, srv.Registry.registerNode(node{Name: "node1", NodeInfo: NodeInfo{Version: "v1", Model: "Raspberry Pi 5"}}))
+
+	h := StatusHandler(srv)
+	req, _ := http.NewRequest(http.MethodGet, "/status", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+  "group": "rack-a",
+  "node": "localhost",
+  "leader": "localhost",
+  "leading": true,
+  "nodes": [
+    { "name": "node1", "version": "v1", "model": "Raspberry Pi 5", "led": { "leds": 0, "maxBrightness": 0 }, "row": 0, "column": 0 },
+    { "name": "node2", "led": { "leds": 0, "maxBrightness": 0 }, "row": 0, "column": 1 }
+  ]
+}`, w.Body.String())
+}
+
+func TestMessageHandler(t *testing.T) {
+	srv := NewServer("localhost", NodeInfo{}, nil, nil, nil, 0, 0, 0, 0, nil, slog.New(slog.DiscardHandler))
+	var evh fakeEventHandler
+	srv.Leader.eventHandler = &evh
+
+	h := MessageHandler(srv, "secret")
+	request := func(method, token string, body io.Reader) int {
+		req, _ := http.NewRequest(method, "/message", body)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+	require.Equal(t, http.StatusAccepted, request(http.MethodPost, "secret", strings.NewReader("DEPLOY OK")))
+	message, ok := evh.publishedMessages.Dequeue()
+	require.True(t, ok)
+	assert.Equal(t, "DEPLOY OK", message)
+
+	// requests without the right token are rejected
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "", strings.NewReader("HACKED")))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "wrong", strings.NewReader("HACKED")))
+	assert.Zero(t, evh.publishedMessages.len())
+
+	// without a token, all requests are rejected
+	h = MessageHandler(srv, "")
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "", strings.NewReader("HACKED")))
+	h = MessageHandler(srv, "secret")
+
+	assert.Equal(t, http.StatusMethodNotAllowed, request(http.MethodGet, "secret", nil))
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPut, "secret", strings.NewReader(strings.Repeat("A", maxMessageSize+1))))
+
+	evh.publishErr = errors.New("publish failed")
+	assert.Equal(t, http.StatusServiceUnavailable, request(http.MethodPut, "secret", strings.NewReader("SOS")))
+}
@@ -0,0 +1,85 @@
+package server
+
+import "github.com/clambin/ledswitcher/internal/schedule"
+
+// Layout maps the nodes, in pattern order, onto a grid, filling it row by row. The zero value places all nodes
+// on a single row.
+//
+// Two-dimensional schedules (schedule.Schedule2D) receive the grid. Other schedules treat the grid as a serpentine
+// line: left to right on even rows, right to left on odd rows.
+type Layout struct {
+	Columns int
+}
+
+// Grid returns the grid holding count nodes. The grid has no more columns than nodes, so every row and column holds
+// at least one node.
+func (l Layout) Grid(count int) schedule.Grid {
+	if l.Columns <= 0 || l.Columns > count {
+		return schedule.Grid{Rows: min(count, 1), Columns: count, LEDs: count}
+	}
+	return schedule.Grid{Rows: (count + l.Columns - 1) / l.Columns, Columns: l.Columns, LEDs: count}
+}
+
+// Position returns the row and column of the node at the specified index.
+func (l Layout) Position(index int) (int, int) {
+	if l.Columns <= 0 {
+		return 0, index
+	}
+	return index / l.Columns, index % l.Columns
+}
+
+// serpentine returns the indices of count nodes, in serpentine order.
+func (l Layout) serpentine(count int) []int {
+	grid := l.Grid(count)
+	order := make([]int, 0, count)
+	for row := range grid.Rows {
+		for i := range grid.Columns {
+			column := i
+			if row%2 == 1 {
+				column = grid.Columns - 1 - i
+			}
+			if index := row*grid.Columns + column; index < count {
+				order = append(order, index)
+			}
+		}
+	}
+	return order
+}
+
+// setCapabilities passes the capabilities of the nodes, in pattern order, to a schedule.CapabilityAware schedule.
+// Schedules that treat the grid as a serpentine line receive the capabilities in the order of the line.
+func (l Layout) setCapabilities(s Schedule, capabilities []schedule.Capabilities) {
+	aware, ok := s.(schedule.CapabilityAware)
+	if !ok {
+		return
+	}
+	if _, ok = s.(schedule.Schedule2D); l.Columns > 0 && !ok {
+		ordered := make([]schedule.Capabilities, 0, len(capabilities))
+		for _, index := range l.serpentine(len(capabilities)) {
+			ordered = append(ordered, capabilities[index])
+		}
+		capabilities = ordered
+	}
+	aware.SetCapabilities(capabilities)
+}
+
+// next returns the next state of count nodes, in pattern order.
+func (l Layout) next(s Schedule, count int) []bool {
+	if l.Columns <= 0 {
+		return s.Next(count)
+	}
+	states := make([]bool, count)
+	if s2d, ok := s.(schedule.Schedule2D); ok {
+		cells := s2d.Next2D(l.Grid(count))
+		for i := range states {
+			row, column := l.Position(i)
+			states[i] = cells[row][column]
+		}
+		return states
+	}
+	next := s.Next(count)
+	for i, index := range l.serpentine(count) {
+		states[index] = next[i]
+	}
+	return states
+}
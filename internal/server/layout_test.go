@@ -0,0 +1,76 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayout_Grid(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout Layout
+		count  int
+		want   schedule.Grid
+	}{
+		{name: "single row", layout: Layout{}, count: 5, want: schedule.Grid{Rows: 1, Columns: 5, LEDs: 5}},
+		{name: "no nodes", layout: Layout{}, count: 0, want: schedule.Grid{}},
+		{name: "full grid", layout: Layout{Columns: 4}, count: 12, want: schedule.Grid{Rows: 3, Columns: 4, LEDs: 12}},
+		{name: "partial grid", layout: Layout{Columns: 4}, count: 10, want: schedule.Grid{Rows: 3, Columns: 4, LEDs: 10}},
+		{name: "fewer nodes than columns", layout: Layout{Columns: 4}, count: 3, want: schedule.Grid{Rows: 1, Columns: 3, LEDs: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.layout.Grid(tt.count))
+		})
+	}
+}
+
+func TestLayout_serpentine(t *testing.T) {
+	assert.Equal(t, []int{0, 1, 2, 5, 4, 3, 6, 7}, Layout{Columns: 3}.serpentine(8))
+	assert.Equal(t, []int{0, 1, 2, 3}, Layout{}.serpentine(4))
+}
+
+func TestLayout_setCapabilities(t *testing.T) {
+	capabilities := []schedule.Capabilities{{LEDs: 0}, {LEDs: 1}, {LEDs: 2}, {LEDs: 3}, {LEDs: 4}}
+
+	// 1D schedules receive the capabilities in the order of the serpentine line
+	var s capabilityAwareSchedule
+	Layout{Columns: 3}.setCapabilities(&s, capabilities)
+	assert.Equal(t, []schedule.Capabilities{{LEDs: 0}, {LEDs: 1}, {LEDs: 2}, {LEDs: 4}, {LEDs: 3}}, s.capabilities)
+
+	// without columns, the order is unchanged
+	Layout{}.setCapabilities(&s, capabilities)
+	assert.Equal(t, capabilities, s.capabilities)
+}
+
+func TestLayout_next(t *testing.T) {
+	layout := Layout{Columns: 3}
+
+	// 1D schedules follow a serpentine line: the 4th LED of the line is the last node of the second row
+	var linear schedule.LinearSchedule
+	for range 2 {
+		_ = linear.Next(5)
+	}
+	assert.Equal(t, []bool{false, false, false, false, true}, layout.next(&linear, 5))
+
+	// 2D schedules receive the grid
+	var columns schedule.ColumnSweepSchedule
+	assert.Equal(t, []bool{true, false, false, true, false}, layout.next(&columns, 5))
+	assert.Equal(t, []bool{false, true, false, false, true}, layout.next(&columns, 5))
+
+	// every rotation of a 2D schedule lights a node, even on a partially filled grid
+	var spiral schedule.SpiralSchedule
+	for range 5 {
+		assert.Contains(t, layout.next(&spiral, 4), true)
+	}
+	columns = schedule.ColumnSweepSchedule{}
+	for range 4 {
+		assert.Contains(t, Layout{Columns: 4}.next(&columns, 2), true)
+	}
+
+	// without columns, all nodes are on a single row
+	var rows schedule.RowSweepSchedule
+	assert.Equal(t, []bool{true, true, true, true, true}, Layout{}.next(&rows, 5))
+}
@@ -1,11 +1,17 @@
 package server
 
 import (
+	"cmp"
 	"context"
+	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"slices"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
 )
 
 type Leader struct {
@@ -14,8 +20,33 @@ type Leader struct {
 	eventHandler
 	logger      *slog.Logger
 	registry    *Registry
+	ledTicker   *time.Ticker
+	order       NodeOrder
+	layout      Layout
+	descriptor  *schedule.Descriptor
+	lastEpoch   *epoch
+	table       *nodeTable
+	protocol    Protocol
 	nodeName    string
+	message     *string
+	nodeCount   int
+	term        uint64
+	sequence    uint64
 	ledInterval time.Duration
+	leadTime    time.Duration
+	warmUp      time.Duration
+	quorum      int
+	lock        sync.Mutex
+	warming     bool
+	// checkpointInterval is the time between checkpoints of the schedule. See SetCheckpoint.
+	checkpointInterval time.Duration
+	checkpointedAt     time.Time
+	// resumed is set once the Leader has resumed the previous leader's pattern
+	resumed bool
+	// randomSeed is set if the Leader chose the descriptor's seed
+	randomSeed bool
+	// epochRenderer follows the pattern of lastEpoch, so a new epoch can continue where it left off
+	epochRenderer *renderer
 }
 
 type Schedule interface {
@@ -26,7 +57,15 @@ func (l *Leader) Run(ctx context.Context) error {
 	l.logger.Debug("leader started")
 	defer l.logger.Debug("leader stopped")
 
+	messages, err := l.messages(ctx, l.logger)
+	if err != nil {
+		return fmt.Errorf("messages: %w", err)
+	}
+
+	l.lock.Lock()
 	ledTicker := time.NewTicker(l.ledInterval)
+	l.ledTicker = ledTicker
+	l.lock.Unlock()
 	defer ledTicker.Stop()
 
 	for {
@@ -35,6 +74,13 @@ func (l *Leader) Run(ctx context.Context) error {
 			if err := l.advance(ctx); err != nil {
 				l.logger.Error("failed to publish next state", "err", err)
 			}
+		case message, ok := <-messages:
+			if !ok {
+				l.logger.Warn("redis subscription closed")
+				return nil
+			}
+			l.logger.Info("message received", "message", message)
+			l.SetMessage(message)
 		case <-ctx.Done():
 			return nil
 		}
@@ -50,25 +96,369 @@ func (l *Leader) SetLeader(leaderName string) {
 	l.leaderName.Store(leaderName)
 }
 
+// SetSchedule replaces the schedule used to determine the next LED states. If a message was set with SetMessage,
+// the new schedule shows that message.
+//
+// Schedules set with SetSchedule can't be rendered by the endpoints, so the Leader publishes their LED states at every
+// rotation. Use SetDescriptor to allow ProtocolEpochs.
+func (l *Leader) SetSchedule(s Schedule) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.setSchedule(s, nil)
+}
+
+// SetDescriptor replaces the schedule with the one described by the Descriptor. If the Descriptor has no seed,
+// a random seed is chosen, so the endpoints can recreate the schedule. If the Descriptor describes the current schedule,
+// the Leader keeps it, so the pattern isn't restarted.
+func (l *Leader) SetDescriptor(d schedule.Descriptor) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.sameDescriptor(d) {
+		return nil
+	}
+	randomSeed := d.Seed == 0
+	if randomSeed {
+		d.Seed = cmp.Or(rand.Uint64(), 1)
+	}
+	s, err := d.New()
+	if err != nil {
+		return err
+	}
+	l.setSchedule(s, &d)
+	l.randomSeed = randomSeed
+	return nil
+}
+
+// sameDescriptor reports whether the Descriptor describes the current schedule. A Descriptor without a seed matches the
+// current schedule if that schedule's seed was chosen at random. Must be called with l.lock held.
+func (l *Leader) sameDescriptor(d schedule.Descriptor) bool {
+	if l.descriptor == nil {
+		return false
+	}
+	if d.Seed == 0 && l.randomSeed {
+		d.Seed = l.descriptor.Seed
+	}
+	return l.descriptor.Equal(d)
+}
+
+func (l *Leader) setSchedule(s Schedule, d *schedule.Descriptor) {
+	l.schedule = s
+	l.descriptor = d
+	l.nodeCount = 0
+	if m, ok := s.(schedule.MessageSchedule); ok && l.message != nil {
+		m.SetMessage(*l.message)
+	}
+}
+
+// SetProtocol changes how the Leader distributes the pattern to the endpoints.
+func (l *Leader) SetProtocol(protocol Protocol) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.protocol = protocol
+}
+
+// SetMessage sets the message shown by schedules that display a text message. The message overrides
+// the schedule's configured message and remains in effect when the schedule is replaced.
+func (l *Leader) SetMessage(message string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.message = &message
+	if m, ok := l.schedule.(schedule.MessageSchedule); ok {
+		m.SetMessage(message)
+	}
+}
+
+// SetInterval changes the delay between LED state changes.
+func (l *Leader) SetInterval(interval time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.ledInterval = interval
+	if l.ledTicker != nil {
+		l.ledTicker.Reset(interval)
+	}
+}
+
+// SetLeadTime sets how far in the future the endpoints apply the published LED states. Applying states at a fixed
+// time, rather than on arrival, keeps nodes on slower links in sync. If zero, endpoints apply states on arrival.
+func (l *Leader) SetLeadTime(leadTime time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.leadTime = leadTime
+}
+
+// SetWarmUp sets how long the Registry must have been running before the Leader publishes. This gives all nodes the
+// time to register after a restart, so the pattern isn't computed for a subset of the nodes. If quorum is positive,
+// the Leader starts publishing as soon as the Registry holds quorum nodes. If warmUp is zero, the Leader publishes
+// immediately.
+func (l *Leader) SetWarmUp(warmUp time.Duration, quorum int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.warmUp = warmUp
+	l.quorum = quorum
+}
+
+// warm returns true if the Registry has seen all active nodes. See SetWarmUp.
+func (l *Leader) warm(nodeCount int) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	warm := l.warmUp <= 0 || l.quorum > 0 && nodeCount >= l.quorum || l.registry.warmFor(l.warmUp)
+	if warm == !l.warming {
+		return warm
+	}
+	l.warming = !warm
+	if l.warming {
+		l.logger.Info("waiting for nodes to register", "nodes", nodeCount, "quorum", l.quorum, "warmUp", l.warmUp)
+	} else {
+		l.logger.Info("registry warmed up", "nodes", nodeCount)
+	}
+	return warm
+}
+
+// SetNodeOrder changes the order of the nodes in the pattern.
+func (l *Leader) SetNodeOrder(order NodeOrder) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.order = order
+}
+
+// SetLayout changes the layout of the nodes.
+func (l *Leader) SetLayout(layout Layout) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.layout = layout
+}
+
+func (l *Leader) getLayout() Layout {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.layout
+}
+
+// orderedNodes returns the active nodes, in pattern order.
+func (l *Leader) orderedNodes() []string {
+	nodes := l.registry.Nodes()
+	l.lock.Lock()
+	order := l.order
+	l.lock.Unlock()
+	order.Sort(nodes, l.registry.NodeInfo)
+	return nodes
+}
+
 func (l *Leader) advance(ctx context.Context) error {
 	if !l.IsLeading() {
 		//l.logger.Debug("not leading")
+		l.lock.Lock()
+		l.lastEpoch, l.epochRenderer = nil, nil
+		l.table = nil
+		l.term = 0
+		l.resumed = false
+		l.lock.Unlock()
 		return nil
 	}
 
-	nodes := l.registry.Nodes()
+	nodes := l.orderedNodes()
 	nodeCount := len(nodes)
-	if nodeCount == 0 {
+	if nodeCount == 0 || !l.warm(nodeCount) {
 		return nil
 	}
+	if err := l.registry.saveSnapshot(ctx); err != nil {
+		l.logger.Warn("failed to save registry snapshot", "err", err)
+	}
+	l.resume(ctx, nodes)
+
+	l.lock.Lock()
+	if e, ok := l.epoch(nodes); ok {
+		l.lock.Unlock()
+		return l.advanceEpoch(ctx, e)
+	}
+	l.lastEpoch, l.epochRenderer = nil, nil
+	if nodeCount != l.nodeCount {
+		if s, ok := l.schedule.(schedule.Resizer); ok && l.nodeCount > 0 {
+			s.Resize(l.nodeCount, nodeCount)
+		}
+		l.nodeCount = nodeCount
+	}
+	l.layout.setCapabilities(l.schedule, l.capabilities(nodes))
+	nextStates := l.layout.next(l.schedule, nodeCount)
+	c, checkpointDue := l.dueCheckpoint(nodeCount)
+	leadTime := l.leadTime
+	l.lock.Unlock()
+
+	if checkpointDue {
+		if err := l.storeCheckpoint(ctx, c, maxCatchUp*c.Interval); err != nil {
+			l.logger.Warn("failed to save checkpoint", "err", err)
+		}
+	}
 
-	nextStates := l.schedule.Next(nodeCount)
-	nodeStates := make(map[string]bool, nodeCount)
+	h, err := l.stamp(ctx)
+	if err != nil {
+		return err
+	}
+	var applyAt time.Time
+	if leadTime > 0 {
+		applyAt = time.Now().Add(leadTime)
+	}
+	encoding := negotiateEncoding(nodes, l.registry.NodeInfo)
+	if encoding == EncodingBinary {
+		return l.publishBinary(ctx, nodes, frame{ApplyAt: applyAt, States: nextStates, Term: h.Term, Sequence: h.Sequence})
+	}
+	l.lock.Lock()
+	l.table = nil
+	l.lock.Unlock()
 
-	slices.Sort(nodes)
+	update := stateUpdate{States: make(ledStates, nodeCount)}
 	for i, state := range nextStates {
-		nodeStates[nodes[i]] = state
+		update.States[nodes[i]] = state
+	}
+	if encoding == EncodingEnvelope {
+		update.ApplyAt, update.header = applyAt, h
+	}
+	return l.publishLEDStates(ctx, update)
+}
+
+// publishBinary publishes the LED states as a frame. If the node order changed since the last frame, it first publishes
+// a new node table. The table's ID is the sequence number of the first frame that uses it.
+func (l *Leader) publishBinary(ctx context.Context, nodes []string, f frame) error {
+	l.lock.Lock()
+	table := l.table
+	l.lock.Unlock()
+	if table == nil || table.Term != f.Term || !slices.Equal(table.Nodes, nodes) {
+		table = &nodeTable{Leader: l.nodeName, Nodes: nodes, Term: f.Term, ID: f.Sequence}
+		if err := l.publishNodeTable(ctx, *table); err != nil {
+			return fmt.Errorf("node table: %w", err)
+		}
+		l.lock.Lock()
+		l.table = table
+		l.lock.Unlock()
+	}
+	f.TableID = table.ID
+	return l.publishFrame(ctx, f)
+}
+
+// stamp returns the header for the next message. The first message after becoming leader starts a new term.
+func (l *Leader) stamp(ctx context.Context) (header, error) {
+	l.lock.Lock()
+	term := l.term
+	l.lock.Unlock()
+	if term == 0 {
+		var err error
+		if term, err = l.nextTerm(ctx); err != nil {
+			return header{}, fmt.Errorf("term: %w", err)
+		}
+		l.logger.Debug("new leadership term", "term", term)
 	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.term != term {
+		l.term, l.sequence = term, 0
+	}
+	l.sequence++
+	return header{Leader: l.nodeName, Term: l.term, Sequence: l.sequence}, nil
+}
 
-	return l.publishLEDStates(ctx, nodeStates)
+// epoch returns the epoch for the current schedule. If the endpoints can't render the schedule locally, ok is false.
+// Must be called with l.lock held.
+func (l *Leader) epoch(nodes []string) (epoch, bool) {
+	if l.protocol != ProtocolEpochs || l.descriptor == nil {
+		return epoch{}, false
+	}
+	d := *l.descriptor
+	if l.message != nil {
+		d.Message = *l.message
+	}
+	e := epoch{
+		Schedule: d,
+		Nodes:    nodes,
+		Columns:  l.layout.Columns,
+		Interval: l.ledInterval,
+	}
+	if _, ok := l.schedule.(schedule.CapabilityAware); ok {
+		e.Capabilities = l.capabilities(nodes)
+	}
+	if l.lastEpoch != nil && l.lastEpoch.sameSchedule(e) {
+		e.Start, e.State = l.lastEpoch.Start, l.lastEpoch.State
+	}
+	return e, true
+}
+
+// advanceEpoch publishes the epoch, if it changed since it was last published, or if the endpoints have been rendering
+// it for epochRefresh rotations. A new epoch starts at least one rotation from now, so the endpoints have time to
+// receive it. Otherwise, the current epoch is republished, so the endpoints know the Leader is alive.
+func (l *Leader) advanceEpoch(ctx context.Context, e epoch) error {
+	l.lock.Lock()
+	e, ok := l.rebase(e, time.Now().Add(e.Interval))
+	l.lock.Unlock()
+	var err error
+	if e.header, err = l.stamp(ctx); err != nil {
+		return err
+	}
+	if !ok {
+		// unchanged: the endpoints are already rendering this epoch
+		return l.republishEpoch(ctx, e)
+	}
+	if err = l.publishEpoch(ctx, e); err != nil {
+		return err
+	}
+	l.logger.Debug("new epoch published", "mode", e.Schedule.Mode, "nodes", len(e.Nodes), "start", e.Start)
+	l.lock.Lock()
+	l.lastEpoch = &e
+	l.lock.Unlock()
+	return nil
+}
+
+// rebase determines the start and the state of the epoch, and whether it should be published. If only the nodes
+// changed, or if an unchanged epoch is due for a refresh, the epoch continues the pattern of the last epoch, rather
+// than restarting it. This requires a schedule.Stateful schedule. Must be called with l.lock held.
+func (l *Leader) rebase(e epoch, after time.Time) (epoch, bool) {
+	r := l.followEpoch(after)
+	s, stateful := r.stateful()
+	unchanged := !e.Start.IsZero()
+	if unchanged && (!stateful || r.ticks < epochRefresh) {
+		return e, false
+	}
+	e.Start, e.State = after, nil
+	if !stateful || !r.Schedule.Equal(e.Schedule) || r.Columns != e.Columns || r.Interval != e.Interval {
+		return e, true
+	}
+	// the renderer is discarded once the new epoch is published, so it's safe to change its schedule
+	l.epochRenderer = nil
+	if resizer, ok := s.(schedule.Resizer); ok && len(r.Nodes) > 0 && len(r.Nodes) != len(e.Nodes) {
+		resizer.Resize(len(r.Nodes), len(e.Nodes))
+	}
+	state, err := s.MarshalState()
+	if err != nil {
+		l.logger.Warn("failed to save schedule state. restarting pattern", "err", err)
+		return e, true
+	}
+	e.Start, e.State = r.next(), state
+	return e, true
+}
+
+// followEpoch returns the renderer of the last epoch, after rendering all patterns up to the specified time. Returns
+// nil if there is no last epoch. Must be called with l.lock held.
+func (l *Leader) followEpoch(until time.Time) *renderer {
+	if l.lastEpoch == nil {
+		return nil
+	}
+	if l.epochRenderer == nil || !l.epochRenderer.Start.Equal(l.lastEpoch.Start) {
+		r, err := newRenderer(*l.lastEpoch, l.nodeName)
+		if err != nil {
+			l.logger.Warn("invalid epoch", "err", err)
+			return nil
+		}
+		l.epochRenderer = r
+	}
+	l.epochRenderer.render(until)
+	return l.epochRenderer
+}
+
+// capabilities returns the capabilities of the nodes, in the specified order.
+func (l *Leader) capabilities(nodes []string) []schedule.Capabilities {
+	capabilities := make([]schedule.Capabilities, len(nodes))
+	for i, name := range nodes {
+		if info, ok := l.registry.NodeInfo(name); ok {
+			capabilities[i] = info.LED
+		}
+	}
+	return capabilities
 }
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"testing"
 	"time"
@@ -49,8 +51,680 @@ func TestLeader(t *testing.T) {
 		{"node1": true, "node2": true},
 		{"node1": false, "node2": false},
 	}
-	ch := evh.ledStates(ctx, logger)
+	ch, err := evh.ledStates(ctx, logger)
+	require.NoError(t, err)
 	for i := range want {
-		assert.Equal(t, want[i], <-ch)
+		assert.Equal(t, want[i], (<-ch).States)
+	}
+}
+
+func TestLeader_SetSchedule(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+
+	registry := Registry{
+		logger: logger,
+		nodes: map[string]time.Time{
+			"node1": time.Now().Add(24 * time.Hour),
+			"node2": time.Now().Add(24 * time.Hour),
+		},
+	}
+
+	linear, err := schedule.New("linear")
+	require.NoError(t, err)
+	leader := Leader{
+		nodeName:     "localhost",
+		eventHandler: &evh,
+		logger:       logger,
+		registry:     &registry,
+		ledInterval:  time.Hour,
+		schedule:     linear,
+	}
+
+	ctx := t.Context()
+	go func() {
+		require.NoError(t, leader.Run(ctx))
+	}()
+	leader.SetLeader("localhost")
+
+	binary, err := schedule.New("binary")
+	require.NoError(t, err)
+	leader.SetSchedule(binary)
+	leader.SetInterval(10 * time.Millisecond)
+
+	ch, err := evh.ledStates(ctx, logger)
+	require.NoError(t, err)
+	assert.Equal(t, ledStates{"node1": false, "node2": true}, (<-ch).States)
+	assert.Equal(t, ledStates{"node1": true, "node2": false}, (<-ch).States)
+}
+
+func TestLeader_CapabilityAware(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+	registry := Registry{logger: logger}
+	require.NoError(t, registry.registerNode(node{Name: "node2", NodeInfo: NodeInfo{LED: schedule.Capabilities{LEDs: 1, MaxBrightness: 1}}}))
+	require.NoError(t, registry.registerNode(node{Name: "node1", NodeInfo: NodeInfo{LED: schedule.Capabilities{LEDs: 1, MaxBrightness: 255}}}))
+	want := []schedule.Capabilities{{LEDs: 1, MaxBrightness: 255}, {LEDs: 1, MaxBrightness: 1}}
+
+	t.Run("states", func(t *testing.T) {
+		var s capabilityAwareSchedule
+		leader := Leader{
+			nodeName:     "localhost",
+			eventHandler: &evh,
+			logger:       logger,
+			registry:     &registry,
+			schedule:     &s,
+		}
+		leader.SetLeader("localhost")
+		require.NoError(t, leader.advance(t.Context()))
+		assert.Equal(t, want, s.capabilities)
+	})
+
+	t.Run("epochs", func(t *testing.T) {
+		leader := Leader{
+			nodeName:     "localhost",
+			eventHandler: &evh,
+			logger:       logger,
+			registry:     &registry,
+			schedule:     &capabilityAwareSchedule{},
+			descriptor:   &schedule.Descriptor{Mode: "linear"},
+			ledInterval:  time.Second,
+			protocol:     ProtocolEpochs,
+		}
+		leader.SetLeader("localhost")
+		require.NoError(t, leader.advance(t.Context()))
+		e, ok := evh.publishedEpochs.Dequeue()
+		require.True(t, ok)
+		assert.Equal(t, want, e.Capabilities)
+
+		// new capabilities start a new epoch
+		require.NoError(t, registry.registerNode(node{Name: "node2", NodeInfo: NodeInfo{LED: schedule.Capabilities{LEDs: 1, MaxBrightness: 255}}}))
+		require.NoError(t, leader.advance(t.Context()))
+		next, ok := evh.publishedEpochs.Dequeue()
+		require.True(t, ok)
+		assert.Equal(t, []schedule.Capabilities{{LEDs: 1, MaxBrightness: 255}, {LEDs: 1, MaxBrightness: 255}}, next.Capabilities)
+		assert.True(t, next.Start.After(e.Start))
+	})
+}
+
+var _ schedule.CapabilityAware = &capabilityAwareSchedule{}
+
+type capabilityAwareSchedule struct {
+	capabilities []schedule.Capabilities
+}
+
+func (c *capabilityAwareSchedule) SetCapabilities(capabilities []schedule.Capabilities) {
+	c.capabilities = capabilities
+}
+
+func (c *capabilityAwareSchedule) Next(count int) []bool {
+	return make([]bool, count)
+}
+
+func TestLeader_Resize(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+	registry := Registry{logger: logger}
+	require.NoError(t, registry.registerNode(node{Name: "node1"}))
+
+	var s resizingSchedule
+	leader := Leader{
+		nodeName:     "localhost",
+		eventHandler: &evh,
+		logger:       logger,
+		registry:     &registry,
+		schedule:     &s,
+	}
+	leader.SetLeader("localhost")
+
+	// the first pattern doesn't resize the schedule
+	require.NoError(t, leader.advance(t.Context()))
+	assert.Empty(t, s.resizes)
+
+	// a new node resizes the schedule once
+	require.NoError(t, registry.registerNode(node{Name: "node2"}))
+	require.NoError(t, leader.advance(t.Context()))
+	require.NoError(t, leader.advance(t.Context()))
+	assert.Equal(t, [][2]int{{1, 2}}, s.resizes)
+
+	// a leaving node resizes the schedule
+	require.NoError(t, registry.registerNode(node{Name: "node1", Leaving: true}))
+	require.NoError(t, leader.advance(t.Context()))
+	assert.Equal(t, [][2]int{{1, 2}, {2, 1}}, s.resizes)
+}
+
+var _ schedule.Resizer = &resizingSchedule{}
+
+type resizingSchedule struct {
+	resizes [][2]int
+}
+
+func (r *resizingSchedule) Resize(oldCount, newCount int) {
+	r.resizes = append(r.resizes, [2]int{oldCount, newCount})
+}
+
+func (r *resizingSchedule) Next(count int) []bool {
+	return make([]bool, count)
+}
+
+func TestLeader_Epochs(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+	registry := Registry{logger: logger}
+	require.NoError(t, registry.registerNode(node{Name: "node1"}))
+
+	leader := Leader{
+		nodeName:     "localhost",
+		eventHandler: &evh,
+		logger:       logger,
+		registry:     &registry,
+		ledInterval:  time.Second,
+		protocol:     ProtocolEpochs,
+	}
+	leader.SetLeader("localhost")
+	require.NoError(t, leader.SetDescriptor(schedule.Descriptor{Mode: "sparkle"}))
+
+	// the first rotation publishes an epoch
+	require.NoError(t, leader.advance(t.Context()))
+	e, ok := evh.publishedEpochs.Dequeue()
+	require.True(t, ok)
+	assert.Equal(t, "sparkle", e.Schedule.Mode)
+	assert.NotZero(t, e.Schedule.Seed)
+	assert.Equal(t, []string{"node1"}, e.Nodes)
+	assert.Equal(t, time.Second, e.Interval)
+	assert.True(t, e.Start.After(time.Now()))
+	assert.Zero(t, evh.publishedLEDStates.len())
+
+	// no changes: the epoch is republished, so the endpoints know the leader is alive, but not stored again
+	stored := evh.storedEpoch
+	require.NoError(t, leader.advance(t.Context()))
+	republished, ok := evh.publishedEpochs.Dequeue()
+	require.True(t, ok)
+	assert.True(t, e.Start.Equal(republished.Start))
+	assert.Greater(t, republished.Sequence, e.Sequence)
+	assert.Same(t, stored, evh.storedEpoch)
+
+	// a new node starts a new epoch
+	require.NoError(t, registry.registerNode(node{Name: "node2"}))
+	require.NoError(t, leader.advance(t.Context()))
+	e, ok = evh.publishedEpochs.Dequeue()
+	require.True(t, ok)
+	assert.Equal(t, []string{"node1", "node2"}, e.Nodes)
+
+	// so does a message
+	leader.SetMessage("SOS")
+	require.NoError(t, leader.advance(t.Context()))
+	e, ok = evh.publishedEpochs.Dequeue()
+	require.True(t, ok)
+	assert.Equal(t, "SOS", e.Schedule.Message)
+
+	// schedules that can't be described fall back to publishing led states
+	linear, err := schedule.New("linear")
+	require.NoError(t, err)
+	leader.SetSchedule(linear)
+	require.NoError(t, leader.advance(t.Context()))
+	assert.Zero(t, evh.publishedEpochs.len())
+	assert.Equal(t, 1, evh.publishedLEDStates.len())
+
+	// a publish failure is retried at the next rotation
+	require.NoError(t, leader.SetDescriptor(schedule.Descriptor{Mode: "linear"}))
+	evh.publishErr = errors.New("publish failed")
+	assert.Error(t, leader.advance(t.Context()))
+	evh.publishErr = nil
+	require.NoError(t, leader.advance(t.Context()))
+	assert.Equal(t, 1, evh.publishedEpochs.len())
+}
+
+func TestLeader_Epochs_Continue(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+	registry := Registry{logger: logger}
+	for _, name := range []string{"node1", "node2", "node3"} {
+		require.NoError(t, registry.registerNode(node{Name: name}))
+	}
+
+	leader := Leader{
+		nodeName:     "localhost",
+		eventHandler: &evh,
+		logger:       logger,
+		registry:     &registry,
+		ledInterval:  time.Second,
+		protocol:     ProtocolEpochs,
+	}
+	leader.SetLeader("localhost")
+	require.NoError(t, leader.SetDescriptor(schedule.Descriptor{Mode: "linear"}))
+
+	require.NoError(t, leader.advance(t.Context()))
+	first, ok := evh.publishedEpochs.Dequeue()
+	require.True(t, ok)
+	assert.Empty(t, first.State)
+
+	// an epoch that has been running for a while is republished, starting from the current state of the schedule
+	first.Start = time.Now().Add(-(epochRefresh + 10) * time.Second)
+	leader.lastEpoch = &first
+	require.NoError(t, leader.advance(t.Context()))
+	refreshed, ok := evh.publishedEpochs.Dequeue()
+	require.True(t, ok)
+	assert.NotEmpty(t, refreshed.State)
+	assert.True(t, refreshed.Start.After(time.Now()))
+	assert.Zero(t, refreshed.Start.Sub(first.Start)%time.Second)
+
+	// endpoints rendering the old epoch and endpoints rendering the new epoch show the same pattern
+	for _, name := range first.Nodes {
+		old, err := newRenderer(first, name)
+		require.NoError(t, err)
+		current, err := newRenderer(refreshed, name)
+		require.NoError(t, err)
+		for i := range 5 {
+			at := refreshed.Start.Add(time.Duration(i) * time.Second)
+			want, _ := old.render(at)
+			got, ok := current.render(at)
+			require.True(t, ok)
+			assert.Equal(t, want, got, name, i)
+		}
+	}
+
+	// a new node continues the pattern, rather than restarting it
+	require.NoError(t, registry.registerNode(node{Name: "node4"}))
+	require.NoError(t, leader.advance(t.Context()))
+	grown, ok := evh.publishedEpochs.Dequeue()
+	require.True(t, ok)
+	assert.Len(t, grown.Nodes, 4)
+	assert.NotEmpty(t, grown.State)
+	assert.Zero(t, grown.Start.Sub(refreshed.Start)%time.Second)
+
+	// schedules without state restart the pattern
+	require.NoError(t, leader.SetDescriptor(schedule.Descriptor{Mode: "sparkle"}))
+	require.NoError(t, leader.advance(t.Context()))
+	restarted, ok := evh.publishedEpochs.Dequeue()
+	require.True(t, ok)
+	assert.Empty(t, restarted.State)
+}
+
+func TestLeader_SetDescriptor(t *testing.T) {
+	leader := Leader{logger: slog.New(slog.DiscardHandler)}
+
+	require.NoError(t, leader.SetDescriptor(schedule.Descriptor{Mode: "sparkle"}))
+	current := leader.schedule
+	seed := leader.descriptor.Seed
+
+	// the same settings keep the current schedule, including its random seed
+	require.NoError(t, leader.SetDescriptor(schedule.Descriptor{Mode: "sparkle"}))
+	assert.Same(t, current, leader.schedule)
+	assert.Equal(t, seed, leader.descriptor.Seed)
+
+	// different settings replace it
+	require.NoError(t, leader.SetDescriptor(schedule.Descriptor{Mode: "sparkle", Seed: 42}))
+	assert.NotSame(t, current, leader.schedule)
+	assert.Equal(t, uint64(42), leader.descriptor.Seed)
+}
+
+func TestLeader_Epochs_States(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+	registry := Registry{logger: logger}
+	require.NoError(t, registry.registerNode(node{Name: "node1"}))
+
+	leader := Leader{
+		nodeName:     "localhost",
+		eventHandler: &evh,
+		logger:       logger,
+		registry:     &registry,
+		ledInterval:  time.Second,
+	}
+	leader.SetLeader("localhost")
+	require.NoError(t, leader.SetDescriptor(schedule.Descriptor{Mode: "linear"}))
+
+	// the default protocol publishes led states
+	require.NoError(t, leader.advance(t.Context()))
+	assert.Zero(t, evh.publishedEpochs.len())
+	assert.Equal(t, 1, evh.publishedLEDStates.len())
+}
+
+func TestLeader_SetLeadTime(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+	registry := Registry{logger: logger}
+	require.NoError(t, registry.registerNode(node{Name: "node1", NodeInfo: NodeInfo{Encodings: []string{EncodingEnvelope}}}))
+
+	s, err := schedule.New("linear")
+	require.NoError(t, err)
+	leader := Leader{
+		nodeName:     "localhost",
+		eventHandler: &evh,
+		logger:       logger,
+		registry:     &registry,
+		schedule:     s,
 	}
+	leader.SetLeader("localhost")
+
+	// without a lead time, states are applied on arrival
+	require.NoError(t, leader.advance(t.Context()))
+	update, ok := evh.publishedLEDStates.Dequeue()
+	require.True(t, ok)
+	assert.Zero(t, update.ApplyAt)
+
+	// with a lead time, states are applied in the future
+	leader.SetLeadTime(time.Minute)
+	require.NoError(t, leader.advance(t.Context()))
+	update, ok = evh.publishedLEDStates.Dequeue()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), update.ApplyAt, time.Second)
+
+	// nodes that can't read the apply time apply states on arrival
+	require.NoError(t, registry.registerNode(node{Name: "node2"}))
+	require.NoError(t, leader.advance(t.Context()))
+	update, ok = evh.publishedLEDStates.Dequeue()
+	require.True(t, ok)
+	assert.Zero(t, update.ApplyAt)
+}
+
+func TestLeader_Term(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+	registry := Registry{logger: logger}
+	require.NoError(t, registry.registerNode(node{Name: "node1", NodeInfo: NodeInfo{Encodings: []string{EncodingEnvelope}}}))
+
+	s, err := schedule.New("linear")
+	require.NoError(t, err)
+	leader := Leader{
+		nodeName:     "localhost",
+		eventHandler: &evh,
+		logger:       logger,
+		registry:     &registry,
+		schedule:     s,
+	}
+
+	// no term: nothing is published
+	evh.termErr = errors.New("redis down")
+	leader.SetLeader("localhost")
+	assert.Error(t, leader.advance(t.Context()))
+	assert.Zero(t, evh.publishedLEDStates.len())
+	evh.termErr = nil
+
+	// messages carry the leader's identity, term and sequence number
+	for _, want := range []header{
+		{Leader: "localhost", Term: 1, Sequence: 1},
+		{Leader: "localhost", Term: 1, Sequence: 2},
+	} {
+		require.NoError(t, leader.advance(t.Context()))
+		update, ok := evh.publishedLEDStates.Dequeue()
+		require.True(t, ok)
+		assert.Equal(t, want, update.header)
+	}
+
+	// regaining leadership starts a new term
+	leader.SetLeader("other")
+	require.NoError(t, leader.advance(t.Context()))
+	leader.SetLeader("localhost")
+	require.NoError(t, leader.advance(t.Context()))
+	update, ok := evh.publishedLEDStates.Dequeue()
+	require.True(t, ok)
+	assert.Equal(t, header{Leader: "localhost", Term: 2, Sequence: 1}, update.header)
+
+	// nodes that don't advertise EncodingEnvelope can't read the header: they receive bare LED states
+	require.NoError(t, registry.registerNode(node{Name: "node2"}))
+	require.NoError(t, leader.advance(t.Context()))
+	update, ok = evh.publishedLEDStates.Dequeue()
+	require.True(t, ok)
+	assert.Zero(t, update.header)
+	payload, err := json.Marshal(update)
+	require.NoError(t, err)
+	var states map[string]bool
+	require.NoError(t, json.Unmarshal(payload, &states))
+	assert.Len(t, states, 2)
+}
+
+func TestLeader_Binary(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+	registry := Registry{logger: logger}
+	binaryNode := NodeInfo{Encodings: []string{EncodingBinary}}
+	require.NoError(t, registry.registerNode(node{Name: "node1", NodeInfo: binaryNode}))
+	require.NoError(t, registry.registerNode(node{Name: "node2", NodeInfo: binaryNode}))
+
+	s, err := schedule.New("linear")
+	require.NoError(t, err)
+	leader := Leader{
+		nodeName:     "localhost",
+		eventHandler: &evh,
+		logger:       logger,
+		registry:     &registry,
+		schedule:     s,
+	}
+	leader.SetLeader("localhost")
+
+	// all nodes support the binary encoding: the first frame is preceded by the node table
+	require.NoError(t, leader.advance(t.Context()))
+	table, ok := evh.publishedTables.Dequeue()
+	require.True(t, ok)
+	assert.Equal(t, nodeTable{Leader: "localhost", Nodes: []string{"node1", "node2"}, Term: 1, ID: 1}, table)
+	f, ok := evh.publishedFrames.Dequeue()
+	require.True(t, ok)
+	assert.Equal(t, frame{States: []bool{false, true}, Term: 1, Sequence: 1, TableID: 1}, f)
+
+	// unchanged node order: no new table
+	require.NoError(t, leader.advance(t.Context()))
+	assert.Zero(t, evh.publishedTables.len())
+	f, ok = evh.publishedFrames.Dequeue()
+	require.True(t, ok)
+	assert.Equal(t, frame{States: []bool{true, false}, Term: 1, Sequence: 2, TableID: 1}, f)
+
+	// a new node: new table
+	require.NoError(t, registry.registerNode(node{Name: "node3", NodeInfo: binaryNode}))
+	require.NoError(t, leader.advance(t.Context()))
+	table, ok = evh.publishedTables.Dequeue()
+	require.True(t, ok)
+	assert.Equal(t, uint64(3), table.ID)
+	assert.Len(t, table.Nodes, 3)
+	f, ok = evh.publishedFrames.Dequeue()
+	require.True(t, ok)
+	assert.Equal(t, uint64(3), f.TableID)
+
+	// a node that only supports the original encoding: fall back to bare LED states, which it can read
+	require.NoError(t, registry.registerNode(node{Name: "node4"}))
+	require.NoError(t, leader.advance(t.Context()))
+	assert.Zero(t, evh.publishedFrames.len())
+	update, ok := evh.publishedLEDStates.Dequeue()
+	require.True(t, ok)
+	payload, err := json.Marshal(update)
+	require.NoError(t, err)
+	var states map[string]bool
+	require.NoError(t, json.Unmarshal(payload, &states))
+	assert.Len(t, states, 4)
+}
+
+func TestLeader_WarmUp(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+	registry := Registry{eventHandler: &evh, logger: logger}
+	go func() { require.NoError(t, registry.Run(t.Context())) }()
+	<-registry.Ready()
+	require.NoError(t, registry.registerNode(node{Name: "node1"}))
+
+	s, err := schedule.New("linear")
+	require.NoError(t, err)
+	leader := Leader{
+		nodeName:     "localhost",
+		eventHandler: &evh,
+		logger:       logger,
+		registry:     &registry,
+		schedule:     s,
+	}
+	leader.SetLeader("localhost")
+	leader.SetWarmUp(time.Hour, 2)
+
+	// registry is cold: nothing is published
+	require.NoError(t, leader.advance(t.Context()))
+	assert.Zero(t, evh.publishedLEDStates.len())
+
+	// quorum reached
+	require.NoError(t, registry.registerNode(node{Name: "node2"}))
+	require.NoError(t, leader.advance(t.Context()))
+	assert.Equal(t, 1, evh.publishedLEDStates.len())
+
+	// warm-up period has passed
+	require.NoError(t, registry.registerNode(node{Name: "node2", Leaving: true}))
+	leader.SetWarmUp(10*time.Millisecond, 2)
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, leader.advance(t.Context()))
+	assert.Equal(t, 2, evh.publishedLEDStates.len())
+}
+
+func TestLeader_SetMessage(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+	registry := Registry{logger: logger}
+	require.NoError(t, registry.registerNode(node{Name: "node1"}))
+
+	s, err := schedule.New("morse")
+	require.NoError(t, err)
+	leader := Leader{
+		nodeName:     "localhost",
+		eventHandler: &evh,
+		logger:       logger,
+		registry:     &registry,
+		ledInterval:  time.Hour,
+		schedule:     s,
+	}
+	leader.SetLeader("localhost")
+
+	go func() {
+		require.NoError(t, leader.Run(t.Context()))
+	}()
+
+	// messages are received from other nodes
+	require.NoError(t, evh.publishMessage(t.Context(), "E"))
+	assert.Eventually(t, func() bool {
+		leader.lock.Lock()
+		defer leader.lock.Unlock()
+		return leader.message != nil
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, leader.advance(t.Context()))
+	update, ok := evh.publishedLEDStates.Dequeue()
+	require.True(t, ok)
+	assert.Equal(t, ledStates{"node1": true}, update.States)
+
+	// a new schedule shows the same message
+	s, err = schedule.New("morse", schedule.WithMessage("T"))
+	require.NoError(t, err)
+	leader.SetSchedule(s)
+	for _, want := range []bool{true, false} {
+		require.NoError(t, leader.advance(t.Context()))
+		update, ok = evh.publishedLEDStates.Dequeue()
+		require.True(t, ok)
+		assert.Equal(t, ledStates{"node1": want}, update.States)
+	}
+}
+
+func TestLeader_Checkpoint(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+	registry := Registry{logger: logger}
+	nodes := []string{"node1", "node2", "node3", "node4"}
+	for _, name := range nodes {
+		require.NoError(t, registry.registerNode(node{Name: name}))
+	}
+	newLeader := func(mode string) *Leader {
+		s, err := schedule.New(mode)
+		require.NoError(t, err)
+		leader := Leader{
+			nodeName:     "localhost",
+			eventHandler: &evh,
+			logger:       logger,
+			registry:     &registry,
+			ledInterval:  time.Second,
+			schedule:     s,
+		}
+		leader.SetLeader("localhost")
+		leader.SetCheckpoint(time.Hour)
+		return &leader
+	}
+	next := func(leader *Leader) []bool {
+		require.NoError(t, leader.advance(t.Context()))
+		update, ok := evh.publishedLEDStates.Dequeue()
+		require.True(t, ok)
+		states := make([]bool, len(nodes))
+		for i, name := range nodes {
+			states[i] = update.States[name]
+		}
+		return states
+	}
+	var reference schedule.LinearSchedule
+	patterns := make([][]bool, 8)
+	for i := range patterns {
+		patterns[i] = reference.Next(len(nodes))
+	}
+
+	// the first rotation saves a checkpoint
+	assert.Equal(t, patterns[0], next(newLeader("linear")))
+	require.NotNil(t, evh.storedCheckpoint)
+	assert.Equal(t, len(nodes), evh.storedCheckpoint.Nodes)
+	assert.Equal(t, time.Second, evh.storedCheckpoint.Interval)
+
+	// a new leader continues the pattern
+	leader := newLeader("linear")
+	assert.Equal(t, patterns[1], next(leader))
+	// the next checkpoint is only due after the checkpoint interval
+	c := *evh.storedCheckpoint
+	assert.Equal(t, patterns[2], next(leader))
+	assert.Equal(t, c, *evh.storedCheckpoint)
+
+	// a new leader catches up on the rotations since the checkpoint was saved
+	evh.storedCheckpoint.At = time.Now().Add(-3 * time.Second)
+	assert.Equal(t, patterns[4], next(newLeader("linear")))
+
+	// a stale checkpoint is ignored
+	evh.storedCheckpoint.At = time.Now().Add(-2 * maxCatchUp * time.Second)
+	assert.Equal(t, patterns[0], next(newLeader("linear")))
+
+	// so is a checkpoint of a different schedule
+	var alternating schedule.AlternatingSchedule
+	assert.Equal(t, alternating.Next(len(nodes)), next(newLeader("alternating")))
+
+	// a leader that doesn't save checkpoints, doesn't resume either
+	evh.storedCheckpoint = nil
+	leader = newLeader("linear")
+	leader.SetCheckpoint(0)
+	assert.Equal(t, patterns[0], next(leader))
+	assert.Nil(t, evh.storedCheckpoint)
+}
+
+func TestLeader_Checkpoint_Epochs(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+	registry := Registry{logger: logger}
+	require.NoError(t, registry.registerNode(node{Name: "node1"}))
+	newLeader := func(checkpoint time.Duration) *Leader {
+		leader := Leader{
+			nodeName:     "localhost",
+			eventHandler: &evh,
+			logger:       logger,
+			registry:     &registry,
+			ledInterval:  time.Second,
+			protocol:     ProtocolEpochs,
+		}
+		leader.SetLeader("localhost")
+		leader.SetCheckpoint(checkpoint)
+		require.NoError(t, leader.SetDescriptor(schedule.Descriptor{Mode: "sparkle"}))
+		return &leader
+	}
+
+	require.NoError(t, newLeader(time.Hour).advance(t.Context()))
+	e, ok := evh.publishedEpochs.Dequeue()
+	require.True(t, ok)
+
+	// a new leader takes over the current epoch, including its seed
+	leader := newLeader(time.Hour)
+	require.NoError(t, leader.advance(t.Context()))
+	republished, ok := evh.publishedEpochs.Dequeue()
+	require.True(t, ok)
+	assert.True(t, e.Start.Equal(republished.Start))
+	assert.Equal(t, e.Schedule.Seed, leader.descriptor.Seed)
+
+	// unless it doesn't resume
+	require.NoError(t, newLeader(0).advance(t.Context()))
+	restarted, ok := evh.publishedEpochs.Dequeue()
+	require.True(t, ok)
+	assert.False(t, e.Start.Equal(restarted.Start))
 }
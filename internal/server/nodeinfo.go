@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net"
+	"os"
+	"strings"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+)
+
+// NodeInfo describes a node. Each node publishes its NodeInfo when it registers.
+type NodeInfo struct {
+	Labels  map[string]string     `json:"labels,omitempty"`
+	Version string                `json:"version,omitempty"`
+	IP      string                `json:"ip,omitempty"`
+	Model   string                `json:"model,omitempty"`
+	LED     schedule.Capabilities `json:"led"`
+	// Encodings are the encodings the node can decode, besides JSON. Nodes that don't advertise any encodings only
+	// receive JSON.
+	Encodings []string `json:"encodings,omitempty"`
+}
+
+const boardModelPath = "/proc/device-tree/model"
+
+// LocalNodeInfo returns the NodeInfo of the local node.
+func LocalNodeInfo(version string, led schedule.Capabilities, labels map[string]string) NodeInfo {
+	return NodeInfo{
+		Version:   version,
+		LED:       led,
+		IP:        localIP(),
+		Model:     boardModel(boardModelPath),
+		Labels:    labels,
+		Encodings: supportedEncodings,
+	}
+}
+
+// boardModel returns the board model, as reported by the device tree. Returns an empty string if the model is not known.
+func boardModel(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	// the device tree terminates strings with a NUL character
+	return strings.TrimRight(string(content), "\x00\r\n ")
+}
+
+// localIP returns the first non-loopback IP address of the node. Returns an empty string if no address is found.
+func localIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	var ip string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+		if ip == "" {
+			ip = ipNet.IP.String()
+		}
+	}
+	return ip
+}
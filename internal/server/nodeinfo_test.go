@@ -0,0 +1,27 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clambin/ledswitcher/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalNodeInfo(t *testing.T) {
+	led := schedule.Capabilities{LEDs: 1, MaxBrightness: 255, Triggers: []string{"none"}}
+	info := LocalNodeInfo("v1.0.0", led, map[string]string{"rack": "a"})
+	assert.Equal(t, "v1.0.0", info.Version)
+	assert.Equal(t, led, info.LED)
+	assert.Equal(t, map[string]string{"rack": "a"}, info.Labels)
+	assert.Contains(t, info.Encodings, EncodingBinary)
+}
+
+func Test_boardModel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model")
+	assert.Empty(t, boardModel(path))
+	require.NoError(t, os.WriteFile(path, []byte("Raspberry Pi 4 Model B Rev 1.4\x00"), 0644))
+	assert.Equal(t, "Raspberry Pi 4 Model B Rev 1.4", boardModel(path))
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// PositionLabel is the node label holding the node's position in the pattern, when ordering nodes by position.
+const PositionLabel = "position"
+
+// NodeOrder determines the order of the nodes in the pattern. The zero value sorts nodes alphabetically.
+type NodeOrder struct {
+	mode  string
+	nodes []string
+}
+
+// NewNodeOrder returns a NodeOrder for the specified mode:
+//
+//   - alphabetical: sort nodes by name
+//   - natural: sort nodes by name, comparing numbers numerically (i.e. pi2 comes before pi10)
+//   - explicit: use the order of the provided nodes
+//   - position: sort nodes by the value of their "position" label
+//
+// With explicit and position, nodes that aren't listed, or don't have a valid position, are appended at the end,
+// in natural order.
+func NewNodeOrder(mode string, nodes []string) (NodeOrder, error) {
+	switch mode {
+	case "", "alphabetical", "natural", "position":
+	case "explicit":
+		if len(nodes) == 0 {
+			return NodeOrder{}, errors.New("explicit order requires a list of nodes")
+		}
+	default:
+		return NodeOrder{}, fmt.Errorf("invalid order: %s", mode)
+	}
+	return NodeOrder{mode: mode, nodes: nodes}, nil
+}
+
+// Sort sorts the nodes in place. info returns the metadata for a node.
+func (o NodeOrder) Sort(nodes []string, info func(string) (NodeInfo, bool)) {
+	switch o.mode {
+	case "natural":
+		slices.SortFunc(nodes, naturalCompare)
+	case "explicit":
+		index := make(map[string]int, len(o.nodes))
+		for i, name := range o.nodes {
+			if _, ok := index[name]; !ok {
+				index[name] = i
+			}
+		}
+		sortByRank(nodes, func(name string) (int, bool) {
+			i, ok := index[name]
+			return i, ok
+		})
+	case "position":
+		sortByRank(nodes, func(name string) (int, bool) {
+			nodeInfo, ok := info(name)
+			if !ok {
+				return 0, false
+			}
+			position, err := strconv.Atoi(nodeInfo.Labels[PositionLabel])
+			return position, err == nil
+		})
+	default:
+		slices.Sort(nodes)
+	}
+}
+
+// sortByRank sorts nodes by their rank. Nodes without a rank are sorted after the ranked nodes, in natural order.
+func sortByRank(nodes []string, rank func(string) (int, bool)) {
+	slices.SortFunc(nodes, func(a, b string) int {
+		rankA, okA := rank(a)
+		rankB, okB := rank(b)
+		switch {
+		case okA && okB:
+			if c := cmp.Compare(rankA, rankB); c != 0 {
+				return c
+			}
+		case okA:
+			return -1
+		case okB:
+			return 1
+		}
+		return naturalCompare(a, b)
+	})
+}
+
+// naturalCompare compares two strings, treating sequences of digits as numbers.
+func naturalCompare(a, b string) int {
+	for a != "" && b != "" {
+		chunkA, restA := nextChunk(a)
+		chunkB, restB := nextChunk(b)
+		if isDigit(chunkA[0]) && isDigit(chunkB[0]) {
+			if c := compareNumbers(chunkA, chunkB); c != 0 {
+				return c
+			}
+		} else if c := cmp.Compare(chunkA, chunkB); c != 0 {
+			return c
+		}
+		a, b = restA, restB
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// nextChunk splits s into its leading run of digits or non-digits, and the remainder.
+func nextChunk(s string) (string, string) {
+	digit := isDigit(s[0])
+	i := 1
+	for i < len(s) && isDigit(s[i]) == digit {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// compareNumbers compares two strings of digits numerically, without overflowing on long numbers.
+func compareNumbers(a, b string) int {
+	trimmedA, trimmedB := trimZeroes(a), trimZeroes(b)
+	if c := cmp.Compare(len(trimmedA), len(trimmedB)); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(trimmedA, trimmedB); c != 0 {
+		return c
+	}
+	// same value: fewer leading zeroes first
+	return cmp.Compare(len(a), len(b))
+}
+
+func trimZeroes(s string) string {
+	for len(s) > 1 && s[0] == '0' {
+		s = s[1:]
+	}
+	return s
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
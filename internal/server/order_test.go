@@ -0,0 +1,83 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNodeOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		nodes   []string
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{name: "default", mode: "", wantErr: assert.NoError},
+		{name: "alphabetical", mode: "alphabetical", wantErr: assert.NoError},
+		{name: "natural", mode: "natural", wantErr: assert.NoError},
+		{name: "position", mode: "position", wantErr: assert.NoError},
+		{name: "explicit", mode: "explicit", nodes: []string{"pi1"}, wantErr: assert.NoError},
+		{name: "explicit without nodes", mode: "explicit", wantErr: assert.Error},
+		{name: "invalid", mode: "invalid", wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewNodeOrder(tt.mode, tt.nodes)
+			tt.wantErr(t, err)
+		})
+	}
+}
+
+func TestNodeOrder_Sort(t *testing.T) {
+	info := map[string]NodeInfo{
+		"pi1":  {Labels: map[string]string{PositionLabel: "3"}},
+		"pi2":  {Labels: map[string]string{PositionLabel: "1"}},
+		"pi10": {Labels: map[string]string{PositionLabel: "2"}},
+		"pi3":  {Labels: map[string]string{PositionLabel: "invalid"}},
+	}
+	tests := []struct {
+		name  string
+		mode  string
+		nodes []string
+		want  []string
+	}{
+		{name: "alphabetical", mode: "alphabetical", want: []string{"pi1", "pi10", "pi11", "pi2", "pi3"}},
+		{name: "natural", mode: "natural", want: []string{"pi1", "pi2", "pi3", "pi10", "pi11"}},
+		{name: "explicit", mode: "explicit", nodes: []string{"pi3", "pi4", "pi1", "pi3"}, want: []string{"pi3", "pi1", "pi2", "pi10", "pi11"}},
+		{name: "position", mode: "position", want: []string{"pi2", "pi10", "pi1", "pi3", "pi11"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := NewNodeOrder(tt.mode, tt.nodes)
+			assert.NoError(t, err)
+			nodes := []string{"pi11", "pi3", "pi10", "pi2", "pi1"}
+			order.Sort(nodes, func(name string) (NodeInfo, bool) {
+				nodeInfo, ok := info[name]
+				return nodeInfo, ok
+			})
+			assert.Equal(t, tt.want, nodes)
+		})
+	}
+}
+
+func Test_naturalCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "pi1", b: "pi1", want: 0},
+		{a: "pi1", b: "pi2", want: -1},
+		{a: "pi2", b: "pi10", want: -1},
+		{a: "pi10", b: "pi2", want: 1},
+		{a: "pi", b: "pi1", want: -1},
+		{a: "pi01", b: "pi1", want: 1},
+		{a: "pi1a", b: "pi1b", want: -1},
+		{a: "a1", b: "b1", want: -1},
+		{a: "1", b: "a", want: -1},
+		{a: "pi99999999999999999999999", b: "pi100000000000000000000000", want: -1},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, naturalCompare(tt.a, tt.b), tt.a+" vs "+tt.b)
+	}
+}
@@ -3,18 +3,64 @@ package server
 import (
 	"cmp"
 	"context"
+	"fmt"
 	"log/slog"
 	"sync"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	nodesAddedMetric = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "ledswitcher",
+		Subsystem: "registry",
+		Name:      "nodes_added_total",
+		Help:      "Number of nodes added to the registry",
+	})
+
+	nodesExpiredMetric = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "ledswitcher",
+		Subsystem: "registry",
+		Name:      "nodes_expired_total",
+		Help:      "Number of expired nodes removed from the registry",
+	})
+
+	nodesLeftMetric = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "ledswitcher",
+		Subsystem: "registry",
+		Name:      "nodes_left_total",
+		Help:      "Number of nodes removed from the registry because they left",
+	})
 )
 
 // A Registry performs two functions. Firstly, it maintains the list of active nodes. Secondly, it registers the local node with the active registry.
 type Registry struct {
 	eventHandler
-	logger         *slog.Logger
-	nodes          map[string]time.Time
-	nodeExpiration time.Duration
-	lock           sync.RWMutex
+	// started is the time the Registry started listening for registrations
+	started time.Time
+	// savedAt is the time the last snapshot was saved
+	savedAt         time.Time
+	logger          *slog.Logger
+	nodes           map[string]time.Time
+	info            map[string]NodeInfo
+	nodeExpiration  time.Duration
+	cleanupInterval time.Duration
+	lock            sync.RWMutex
+	subscribed      readiness
+	// useSnapshot enables saving the registered nodes in Redis, and seeding the Registry from the saved snapshot
+	useSnapshot bool
+	// seeded is true if the Registry was seeded from a snapshot
+	seeded bool
+	// changed is true if nodes were added or removed since the last snapshot
+	changed bool
+}
+
+// registration is a registered node, as stored in a snapshot of the Registry.
+type registration struct {
+	Expires time.Time `json:"expires"`
+	Name    string    `json:"name"`
+	Info    NodeInfo  `json:"info"`
 }
 
 // Run listens for incoming 'node' events and registers them. Old nodes are removed regularly.
@@ -22,7 +68,19 @@ func (r *Registry) Run(ctx context.Context) error {
 	r.logger.Debug("registry started")
 	defer r.logger.Debug("registry stopped")
 
-	ch := r.eventHandler.nodes(ctx, r.logger)
+	ch, err := r.eventHandler.nodes(ctx, r.logger)
+	if err != nil {
+		return fmt.Errorf("nodes: %w", err)
+	}
+	r.lock.Lock()
+	r.started = time.Now()
+	r.lock.Unlock()
+	r.seed(ctx)
+	r.subscribed.set()
+
+	cleanupTicker := time.NewTicker(cmp.Or(r.cleanupInterval, 10*time.Minute))
+	defer cleanupTicker.Stop()
+
 	for {
 		select {
 		case info, ok := <-ch:
@@ -33,7 +91,7 @@ func (r *Registry) Run(ctx context.Context) error {
 			if err := r.registerNode(info); err != nil {
 				r.logger.Error("failed to register node", "error", err)
 			}
-		case <-time.After(10 * time.Minute):
+		case <-cleanupTicker.C:
 			r.cleanup()
 		case <-ctx.Done():
 			return nil
@@ -41,16 +99,120 @@ func (r *Registry) Run(ctx context.Context) error {
 	}
 }
 
-func (r *Registry) registerNode(info node) error {
+// SetSnapshot enables or disables snapshots. With snapshots enabled, the leader saves the registered nodes in Redis,
+// and a Registry that starts seeds its nodes from the saved snapshot, rather than waiting for all nodes to register.
+// Seeded nodes expire at the time recorded in the snapshot, unless they register again.
+func (r *Registry) SetSnapshot(enabled bool) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
+	r.useSnapshot = enabled
+}
+
+// seed adds the nodes of the saved snapshot, if snapshots are enabled.
+func (r *Registry) seed(ctx context.Context) {
+	r.lock.RLock()
+	useSnapshot := r.useSnapshot
+	r.lock.RUnlock()
+	if !useSnapshot {
+		return
+	}
+	registrations, err := r.loadRegistry(ctx)
+	if err != nil {
+		r.logger.Warn("failed to load registry snapshot", "err", err)
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.init()
+	var seeded int
+	for _, reg := range registrations {
+		if _, ok := r.nodes[reg.Name]; ok || time.Now().After(reg.Expires) {
+			continue
+		}
+		r.nodes[reg.Name] = reg.Expires
+		r.info[reg.Name] = reg.Info
+		seeded++
+	}
+	if seeded > 0 {
+		r.seeded = true
+		r.logger.Info("registry seeded from snapshot", "nodes", seeded)
+	}
+}
+
+// saveSnapshot saves the registered nodes, if snapshots are enabled and the nodes changed since the last snapshot,
+// or if the last snapshot is about to expire.
+func (r *Registry) saveSnapshot(ctx context.Context) error {
+	r.lock.RLock()
+	expiration := r.expiration()
+	if !r.useSnapshot || !r.changed && time.Since(r.savedAt) < expiration/2 {
+		r.lock.RUnlock()
+		return nil
+	}
+	registrations := make([]registration, 0, len(r.nodes))
+	for name, expires := range r.nodes {
+		registrations = append(registrations, registration{Name: name, Info: r.info[name], Expires: expires})
+	}
+	r.lock.RUnlock()
+
+	if err := r.saveRegistry(ctx, registrations, expiration); err != nil {
+		return fmt.Errorf("registry snapshot: %w", err)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.changed = false
+	r.savedAt = time.Now()
+	return nil
+}
+
+// warmFor returns true if the Registry has been listening for registrations for at least d, or was seeded from
+// a snapshot. A warm Registry has seen all active nodes.
+func (r *Registry) warmFor(d time.Duration) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.seeded || !r.started.IsZero() && time.Since(r.started) >= d
+}
+
+func (r *Registry) expiration() time.Duration {
+	return cmp.Or(r.nodeExpiration, 5*time.Minute)
+}
+
+func (r *Registry) init() {
 	if r.nodes == nil {
 		r.nodes = make(map[string]time.Time)
+		r.info = make(map[string]NodeInfo)
+	}
+}
+
+// Ready returns a channel that is closed once the Registry's subscription is live.
+func (r *Registry) Ready() <-chan struct{} {
+	return r.subscribed.ready()
+}
+
+func (r *Registry) registerNode(info node) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.init()
+	if info.Leaving {
+		if _, ok := r.nodes[info.Name]; ok {
+			delete(r.nodes, info.Name)
+			delete(r.info, info.Name)
+			r.changed = true
+			nodesLeftMetric.Inc()
+			r.logger.Info("node left", "name", info.Name)
+		}
+		return nil
+	}
+	_, known := r.nodes[info.Name]
+	if !known {
+		r.logger.Info("registering new node", "name", info.Name)
+		nodesAddedMetric.Inc()
+		r.changed = true
 	}
-	if _, ok := r.nodes[string(info)]; !ok {
-		r.logger.Info("registering new node", "name", info)
+	r.nodes[info.Name] = time.Now().Add(r.expiration())
+	if !info.nameOnly || !known {
+		// a bare name doesn't replace the NodeInfo of the node's full registration
+		r.info[info.Name] = info.NodeInfo
 	}
-	r.nodes[string(info)] = time.Now().Add(cmp.Or(r.nodeExpiration, 5*time.Minute))
 	return nil
 }
 
@@ -60,6 +222,9 @@ func (r *Registry) cleanup() {
 	for name, expiration := range r.nodes {
 		if time.Now().After(expiration) {
 			delete(r.nodes, name)
+			delete(r.info, name)
+			r.changed = true
+			nodesExpiredMetric.Inc()
 			r.logger.Debug("removed expired node", "name", name)
 		}
 	}
@@ -77,10 +242,21 @@ func (r *Registry) Nodes() []string {
 	return nodes
 }
 
+// NodeInfo returns the metadata of a registered node.
+func (r *Registry) NodeInfo(name string) (NodeInfo, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	info, ok := r.info[name]
+	return info, ok
+}
+
+// A Registrant registers the local node with the active registry. When it stops, it announces that the node is leaving,
+// so registries drop the node immediately, rather than waiting for it to expire.
 type Registrant struct {
 	eventHandler
 	logger   *slog.Logger
 	nodeName string
+	info     NodeInfo
 	interval time.Duration
 }
 
@@ -94,11 +270,20 @@ func (r *Registrant) Run(ctx context.Context) error {
 	for {
 		select {
 		case <-registrationTicker.C:
-			if err := r.publishNode(ctx, r.nodeName); err != nil {
+			if err := r.publishNode(ctx, node{Name: r.nodeName, NodeInfo: r.info}); err != nil {
 				r.logger.Error("failed to register node", "err", err)
 			}
 		case <-ctx.Done():
+			r.leave(ctx)
 			return nil
 		}
 	}
 }
+
+func (r *Registrant) leave(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+	defer cancel()
+	if err := r.publishNode(ctx, node{Name: r.nodeName, Leaving: true}); err != nil {
+		r.logger.Warn("failed to announce leaving", "err", err)
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"log/slog"
 	"testing"
 	"time"
@@ -46,6 +47,53 @@ func TestRegistry(t *testing.T) {
 	assert.Equal(t, registrant.nodeName, nodes[0])
 }
 
+func TestRegistrant_leave(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+
+	r := Registry{
+		eventHandler:   &evh,
+		nodeExpiration: time.Hour,
+		logger:         logger,
+	}
+	go func() {
+		require.NoError(t, r.Run(t.Context()))
+	}()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	registrant := Registrant{
+		nodeName:     "localhost",
+		eventHandler: &evh,
+		interval:     10 * time.Millisecond,
+		logger:       logger,
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, registrant.Run(ctx))
+	}()
+
+	require.Eventually(t, func() bool { return len(r.Nodes()) == 1 }, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+	assert.Eventually(t, func() bool { return len(r.Nodes()) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestRegistry_nameOnly(t *testing.T) {
+	r := Registry{nodeExpiration: time.Hour, logger: slog.New(slog.DiscardHandler)}
+
+	// nodes that only publish their name are registered without NodeInfo
+	require.NoError(t, r.registerNode(node{Name: "node1", nameOnly: true}))
+	assert.Equal(t, []string{"node1"}, r.Nodes())
+
+	// a bare name doesn't clear the NodeInfo of a full registration
+	require.NoError(t, r.registerNode(node{Name: "node2", NodeInfo: NodeInfo{Version: "v1"}}))
+	require.NoError(t, r.registerNode(node{Name: "node2", nameOnly: true}))
+	info, ok := r.NodeInfo("node2")
+	require.True(t, ok)
+	assert.Equal(t, "v1", info.Version)
+}
+
 func TestRegistry_cleanup(t *testing.T) {
 	r := Registry{
 		nodes:  map[string]time.Time{"localhost": {}},
@@ -55,3 +103,69 @@ func TestRegistry_cleanup(t *testing.T) {
 	r.cleanup()
 	assert.Empty(t, r.nodes)
 }
+
+func TestRegistry_Run_cleanup(t *testing.T) {
+	r := Registry{
+		eventHandler:    &fakeEventHandler{},
+		nodes:           map[string]time.Time{"localhost": {}},
+		cleanupInterval: 10 * time.Millisecond,
+		logger:          slog.New(slog.DiscardHandler),
+	}
+	go func() {
+		require.NoError(t, r.Run(t.Context()))
+	}()
+	assert.Eventually(t, func() bool {
+		r.lock.RLock()
+		defer r.lock.RUnlock()
+		return len(r.nodes) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRegistry_Snapshot(t *testing.T) {
+	var evh fakeEventHandler
+	logger := slog.New(slog.DiscardHandler)
+
+	// without snapshots, nothing is saved
+	r := Registry{eventHandler: &evh, nodeExpiration: time.Hour, logger: logger}
+	require.NoError(t, r.registerNode(node{Name: "node1"}))
+	require.NoError(t, r.saveSnapshot(t.Context()))
+	assert.Empty(t, evh.storedRegistry)
+
+	r.SetSnapshot(true)
+	require.NoError(t, r.registerNode(node{Name: "node2", NodeInfo: NodeInfo{Version: "v1"}}))
+	require.NoError(t, r.saveSnapshot(t.Context()))
+	assert.Len(t, evh.storedRegistry, 2)
+
+	// unchanged: the snapshot isn't saved again
+	evh.storedRegistry = nil
+	require.NoError(t, r.saveSnapshot(t.Context()))
+	assert.Empty(t, evh.storedRegistry)
+	require.NoError(t, r.registerNode(node{Name: "node1", Leaving: true}))
+	require.NoError(t, r.saveSnapshot(t.Context()))
+	require.Len(t, evh.storedRegistry, 1)
+	assert.Equal(t, "node2", evh.storedRegistry[0].Name)
+
+	// a new registry is seeded from the snapshot, and is immediately warm
+	evh.storedRegistry = append(evh.storedRegistry, registration{Name: "expired", Expires: time.Now().Add(-time.Minute)})
+	r2 := Registry{eventHandler: &evh, nodeExpiration: time.Hour, logger: logger}
+	r2.SetSnapshot(true)
+	assert.False(t, r2.warmFor(time.Hour))
+	go func() { require.NoError(t, r2.Run(t.Context())) }()
+	<-r2.Ready()
+	assert.Equal(t, []string{"node2"}, r2.Nodes())
+	info, ok := r2.NodeInfo("node2")
+	require.True(t, ok)
+	assert.Equal(t, "v1", info.Version)
+	assert.True(t, r2.warmFor(time.Hour))
+}
+
+func TestRegistry_warmFor(t *testing.T) {
+	var evh fakeEventHandler
+	r := Registry{eventHandler: &evh, logger: slog.New(slog.DiscardHandler)}
+	assert.False(t, r.warmFor(0))
+	go func() { require.NoError(t, r.Run(t.Context())) }()
+	<-r.Ready()
+	assert.True(t, r.warmFor(0))
+	assert.False(t, r.warmFor(time.Hour))
+	assert.Eventually(t, func() bool { return r.warmFor(50 * time.Millisecond) }, time.Second, 10*time.Millisecond)
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"log/slog"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 )
 
 type Server struct {
+	events *redisEventHandler
 	Leader
 	Endpoint
 	Registrant
@@ -20,27 +22,36 @@ type Server struct {
 
 func NewServer(
 	nodeName string,
+	info NodeInfo,
 	schedule Schedule,
-	client *redis.Client,
+	client redis.UniversalClient,
 	led LED,
 	ledInterval time.Duration,
 	registrationInterval time.Duration,
 	nodeExpiration time.Duration,
+	cleanupInterval time.Duration,
 	r prometheus.Registerer,
 	logger *slog.Logger,
 ) *Server {
 	if r != nil {
-		r.MustRegister(publishedEventsMetric, receivedEventsMetrics)
+		r.MustRegister(
+			publishedEventsMetric, receivedEventsMetrics, rejectedEventsMetric, unverifiedEventsMetric,
+			nodesAddedMetric, nodesExpiredMetric, nodesLeftMetric,
+			arrivalSlackMetric, lateStatesMetric, staleMessagesMetric, fallbackMetric,
+		)
 	}
-	evh := &redisEventHandler{Client: client}
+	evh := &redisEventHandler{UniversalClient: client}
 	server := Server{
+		events: evh,
 		Registry: Registry{
-			eventHandler:   evh,
-			nodeExpiration: nodeExpiration,
-			logger:         logger.With("component", "registry"),
+			eventHandler:    evh,
+			nodeExpiration:  nodeExpiration,
+			cleanupInterval: cleanupInterval,
+			logger:          logger.With("component", "registry"),
 		},
 		Registrant: Registrant{
 			nodeName:     nodeName,
+			info:         info,
 			interval:     registrationInterval,
 			eventHandler: evh,
 			logger:       logger.With("component", "registrant"),
@@ -64,11 +75,92 @@ func NewServer(
 	return &server
 }
 
+// SetSigner sets the Signer used to sign published messages and to verify received messages. If nil, messages are
+// not signed, and received messages are accepted without verification.
+func (s *Server) SetSigner(signer *Signer) {
+	s.events.signer.Store(signer)
+}
+
+// SetGroup sets the group of the Server. Groups sharing the same Redis are independent: each group has its own leader,
+// registry and schedule. SetGroup must be called before Run.
+func (s *Server) SetGroup(group string) {
+	s.events.group = group
+}
+
+// Run starts the Server. The Registrant and Leader are only started once the Registry and Endpoint subscriptions
+// are live, so no registrations or LED states are published before someone is listening.
 func (s *Server) Run(ctx context.Context) error {
-	var g errgroup.Group
+	g, subCtx := errgroup.WithContext(ctx)
 	g.Go(func() error { return s.Registry.Run(ctx) })
-	g.Go(func() error { return s.Registrant.Run(ctx) })
 	g.Go(func() error { return s.Endpoint.Run(ctx) })
+	for _, ready := range []<-chan struct{}{s.Registry.Ready(), s.Endpoint.Ready()} {
+		select {
+		case <-ready:
+		case <-subCtx.Done():
+			return g.Wait()
+		}
+	}
+	g.Go(func() error { return s.Registrant.Run(ctx) })
 	g.Go(func() error { return s.Leader.Run(ctx) })
 	return g.Wait()
 }
+
+// Status is the status of a Server, as reported by the status API.
+type Status struct {
+	Group  string `json:"group,omitempty"`
+	Node   string `json:"node"`
+	Leader string `json:"leader"`
+	// Nodes are the registered nodes, in the order of the pattern
+	Nodes   []NodeStatus `json:"nodes"`
+	Leading bool         `json:"leading"`
+}
+
+// NodeStatus is the status of a registered node.
+type NodeStatus struct {
+	NodeInfo
+	Name   string `json:"name"`
+	Row    int    `json:"row"`
+	Column int    `json:"column"`
+}
+
+// Status returns the current status of the Server.
+func (s *Server) Status() Status {
+	status := Status{
+		Group:   s.events.group,
+		Node:    s.Leader.nodeName,
+		Leading: s.IsLeading(),
+	}
+	if leader := s.leaderName.Load(); leader != nil {
+		status.Leader = leader.(string)
+	}
+	nodes := s.Leader.orderedNodes()
+	layout := s.Leader.getLayout()
+	status.Nodes = make([]NodeStatus, 0, len(nodes))
+	for i, name := range nodes {
+		info, _ := s.Registry.NodeInfo(name)
+		row, column := layout.Position(i)
+		status.Nodes = append(status.Nodes, NodeStatus{Name: name, NodeInfo: info, Row: row, Column: column})
+	}
+	return status
+}
+
+// readiness signals that a component is ready. The zero value is ready to use.
+type readiness struct {
+	once   sync.Once
+	closer sync.Once
+	ch     chan struct{}
+}
+
+func (r *readiness) ready() <-chan struct{} {
+	r.once.Do(r.init)
+	return r.ch
+}
+
+func (r *readiness) set() {
+	r.once.Do(r.init)
+	r.closer.Do(func() { close(r.ch) })
+}
+
+func (r *readiness) init() {
+	r.ch = make(chan struct{})
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -25,12 +26,14 @@ func TestServer(t *testing.T) {
 	logger := slog.New(slog.DiscardHandler) //slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	server := NewServer(
 		"localhost",
+		NodeInfo{},
 		s,
 		nil,
 		&led,
 		10*time.Millisecond,
 		10*time.Millisecond,
 		time.Hour,
+		time.Hour,
 		r,
 		logger,
 	)
@@ -47,6 +50,19 @@ func TestServer(t *testing.T) {
 	assert.Eventually(t, func() bool { return led.written() > 2 }, time.Second, 10*time.Millisecond)
 }
 
+func TestServer_SubscribeFailure(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	server := NewServer("localhost", NodeInfo{}, nil, nil, nil, 10*time.Millisecond, 10*time.Millisecond, time.Hour, time.Hour, nil, logger)
+	evh := fakeEventHandler{subscribeErr: errors.New("subscribe failed")}
+	server.Endpoint.eventHandler = &evh
+	server.Registry.eventHandler = &evh
+	server.Registrant.eventHandler = &evh
+	server.Leader.eventHandler = &evh
+
+	assert.Error(t, server.Run(t.Context()))
+	assert.Zero(t, evh.publishedNodes.len())
+}
+
 func TestServer_Slow(t *testing.T) {
 	t.Skip()
 	ctx := t.Context()
@@ -72,12 +88,14 @@ func TestServer_Slow(t *testing.T) {
 		registries[i] = prometheus.NewPedanticRegistry()
 		servers[i] = NewServer(
 			nodeName,
+			NodeInfo{},
 			s,
 			client,
 			leds[i],
 			500*time.Millisecond,
 			500*time.Millisecond,
 			time.Hour,
+			time.Hour,
 			registries[i],
 			l,
 		)
@@ -100,7 +118,7 @@ func TestServer_Slow(t *testing.T) {
 
 	count, err := testutil.GatherAndCount(registries[0])
 	require.NoError(t, err)
-	assert.Equal(t, 4, count)
+	assert.Equal(t, 10, count)
 }
 
 var _ LED = &fakeLED{}
@@ -124,35 +142,197 @@ func (f *fakeLED) written() int64 {
 	return f.writes.Load()
 }
 
+var _ TriggerLED = &fakeTriggerLED{}
+
+type fakeTriggerLED struct {
+	fakeLED
+	trigger string
+	lock    sync.Mutex
+}
+
+func (f *fakeTriggerLED) GetActiveMode() (string, error) {
+	return f.getTrigger(), nil
+}
+
+func (f *fakeTriggerLED) SetActiveMode(mode string) error {
+	f.setTrigger(mode)
+	return nil
+}
+
+func (f *fakeTriggerLED) getTrigger() string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.trigger
+}
+
+func (f *fakeTriggerLED) setTrigger(mode string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.trigger = mode
+}
+
 var _ eventHandler = &fakeEventHandler{}
 
 type fakeEventHandler struct {
 	lock               sync.Mutex
-	publishedLEDStates queue[ledStates]
+	publishedLEDStates queue[stateUpdate]
 	publishedNodes     queue[node]
+	publishedMessages  queue[string]
+	publishedEpochs    queue[epoch]
+	publishedTables    queue[nodeTable]
+	publishedFrames    queue[frame]
+	storedEpoch        *epoch
+	storedRegistry     []registration
+	storedCheckpoint   *checkpoint
+	term               uint64
+	termErr            error
 	pingErr            error
+	subscribeErr       error
+	publishErr         error
 }
 
-func (f *fakeEventHandler) publishLEDStates(_ context.Context, states ledStates) error {
+func (f *fakeEventHandler) publishLEDStates(_ context.Context, update stateUpdate) error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
-	f.publishedLEDStates.Queue(states)
+	f.publishedLEDStates.Queue(update)
 	return nil
 }
 
-func (f *fakeEventHandler) ledStates(ctx context.Context, _ *slog.Logger) <-chan ledStates {
-	return drainQueue(ctx, f.publishedLEDStates.Dequeue)
+func (f *fakeEventHandler) publishNodeTable(_ context.Context, t nodeTable) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.publishErr != nil {
+		return f.publishErr
+	}
+	f.publishedTables.Queue(t)
+	return nil
+}
+
+func (f *fakeEventHandler) publishFrame(_ context.Context, fr frame) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.publishedFrames.Queue(fr)
+	return nil
+}
+
+func (f *fakeEventHandler) ledStates(ctx context.Context, _ *slog.Logger) (<-chan stateUpdate, error) {
+	if f.subscribeErr != nil {
+		return nil, f.subscribeErr
+	}
+	return drainQueue(ctx, f.publishedLEDStates.Dequeue), nil
 }
 
-func (f *fakeEventHandler) publishNode(_ context.Context, info string) error {
+func (f *fakeEventHandler) publishNode(_ context.Context, info node) error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
-	f.publishedNodes.Queue(node(info))
+	f.publishedNodes.Queue(info)
 	return nil
 }
 
-func (f *fakeEventHandler) nodes(ctx context.Context, _ *slog.Logger) <-chan node {
-	return drainQueue(ctx, f.publishedNodes.Dequeue)
+func (f *fakeEventHandler) nodes(ctx context.Context, _ *slog.Logger) (<-chan node, error) {
+	if f.subscribeErr != nil {
+		return nil, f.subscribeErr
+	}
+	return drainQueue(ctx, f.publishedNodes.Dequeue), nil
+}
+
+func (f *fakeEventHandler) publishMessage(_ context.Context, message string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.publishErr != nil {
+		return f.publishErr
+	}
+	f.publishedMessages.Queue(message)
+	return nil
+}
+
+func (f *fakeEventHandler) messages(ctx context.Context, _ *slog.Logger) (<-chan string, error) {
+	if f.subscribeErr != nil {
+		return nil, f.subscribeErr
+	}
+	return drainQueue(ctx, f.publishedMessages.Dequeue), nil
+}
+
+func (f *fakeEventHandler) publishEpoch(_ context.Context, e epoch) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.publishErr != nil {
+		return f.publishErr
+	}
+	f.storedEpoch = &e
+	f.publishedEpochs.Queue(e)
+	return nil
+}
+
+func (f *fakeEventHandler) republishEpoch(_ context.Context, e epoch) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.publishErr != nil {
+		return f.publishErr
+	}
+	f.publishedEpochs.Queue(e)
+	return nil
+}
+
+func (f *fakeEventHandler) epochs(ctx context.Context, _ *slog.Logger) (<-chan epoch, error) {
+	if f.subscribeErr != nil {
+		return nil, f.subscribeErr
+	}
+	return drainQueue(ctx, f.publishedEpochs.Dequeue), nil
+}
+
+func (f *fakeEventHandler) currentEpoch(_ context.Context) (epoch, bool, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.storedEpoch == nil {
+		return epoch{}, false, nil
+	}
+	return *f.storedEpoch, true, nil
+}
+
+func (f *fakeEventHandler) nextTerm(_ context.Context) (uint64, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.termErr != nil {
+		return 0, f.termErr
+	}
+	f.term++
+	return f.term, nil
+}
+
+func (f *fakeEventHandler) saveRegistry(_ context.Context, registrations []registration, _ time.Duration) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.publishErr != nil {
+		return f.publishErr
+	}
+	f.storedRegistry = registrations
+	return nil
+}
+
+func (f *fakeEventHandler) loadRegistry(_ context.Context) ([]registration, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.storedRegistry, nil
+}
+
+func (f *fakeEventHandler) storeCheckpoint(_ context.Context, c checkpoint, _ time.Duration) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.publishErr != nil {
+		return f.publishErr
+	}
+	f.storedCheckpoint = &c
+	return nil
+}
+
+func (f *fakeEventHandler) loadCheckpoint(_ context.Context) (checkpoint, bool, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.storedCheckpoint == nil {
+		return checkpoint{}, false, nil
+	}
+	return *f.storedCheckpoint, true, nil
 }
 
 func (f *fakeEventHandler) ping(_ context.Context) error {
@@ -187,6 +367,12 @@ func (q *queue[T]) Queue(value T) {
 	q.items = append(q.items, value)
 }
 
+func (q *queue[T]) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.items)
+}
+
 func (q *queue[T]) Dequeue() (value T, ok bool) {
 	q.lock.Lock()
 	defer q.lock.Unlock()
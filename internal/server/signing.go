@@ -0,0 +1,107 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// signedMarker is the first byte of a signed message. It is below 0x20, so signed messages can't be confused with JSON,
+// and differs from wireVersion, so they can't be confused with binary messages.
+const signedMarker byte = 0x1f
+
+// signatureWindow is how far the time a message was signed may be from the receiver's clock. Older messages are
+// rejected, so a captured message can't be replayed later. The nodes' clocks must be synchronized (e.g. using NTP).
+const signatureWindow = time.Minute
+
+// signedHeaderSize is the size of the marker, the signature and the signing time that precede the payload.
+const signedHeaderSize = 1 + sha256.Size + 8
+
+var (
+	errUnsigned         = errors.New("message not signed")
+	errInvalidSignature = errors.New("invalid signature")
+	errExpired          = errors.New("signature expired")
+)
+
+// A Signer signs the messages a node publishes and verifies the messages it receives, using HMAC-SHA256 with a shared key.
+//
+// To rotate the key without interruption, first add the new key to the accepted keys of all nodes, then make it the
+// signing key, and finally remove the old key.
+type Signer struct {
+	key      []byte
+	accepted [][]byte
+}
+
+// NewSigner returns a Signer that signs messages with key. Messages signed with key, or with any of the accepted keys,
+// pass verification.
+func NewSigner(key []byte, accepted ...[]byte) (*Signer, error) {
+	if len(key) == 0 {
+		return nil, errors.New("signing key must not be empty")
+	}
+	s := Signer{key: key, accepted: [][]byte{key}}
+	for _, k := range accepted {
+		if len(k) == 0 {
+			return nil, errors.New("accepted key must not be empty")
+		}
+		s.accepted = append(s.accepted, k)
+	}
+	return &s, nil
+}
+
+// sign returns the signed message. The signature covers the channel, so a message can't be replayed on another channel,
+// and the time the message was signed, so it can't be replayed later.
+func (s *Signer) sign(channel string, payload []byte) []byte {
+	return s.signAt(channel, payload, time.Now())
+}
+
+func (s *Signer) signAt(channel string, payload []byte, at time.Time) []byte {
+	signed := make([]byte, 0, signedHeaderSize+len(payload))
+	signed = append(signed, signedMarker)
+	signed = append(signed, mac(s.key, channel, at.UnixNano(), payload)...)
+	signed = binary.BigEndian.AppendUint64(signed, uint64(at.UnixNano()))
+	return append(signed, payload...)
+}
+
+// verify checks the signature of a signed message and returns its payload. If maxAge is not zero, messages signed more
+// than maxAge ago, or more than maxAge in the future, are rejected.
+func (s *Signer) verify(channel string, signed []byte, maxAge time.Duration) ([]byte, error) {
+	signature, at, payload, ok := unwrap(signed)
+	if !ok {
+		return nil, errUnsigned
+	}
+	valid := false
+	for _, key := range s.accepted {
+		if hmac.Equal(signature, mac(key, channel, at, payload)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, errInvalidSignature
+	}
+	if age := time.Since(time.Unix(0, at)); maxAge > 0 && (age > maxAge || age < -maxAge) {
+		return nil, errExpired
+	}
+	return payload, nil
+}
+
+// unwrap splits a signed message into its signature, signing time (in Unix nanoseconds) and payload. ok is false if
+// the message isn't signed.
+func unwrap(signed []byte) (signature []byte, at int64, payload []byte, ok bool) {
+	if len(signed) < signedHeaderSize || signed[0] != signedMarker {
+		return nil, 0, signed, false
+	}
+	at = int64(binary.BigEndian.Uint64(signed[1+sha256.Size : signedHeaderSize]))
+	return signed[1 : 1+sha256.Size], at, signed[signedHeaderSize:], true
+}
+
+func mac(key []byte, channel string, at int64, payload []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(channel))
+	h.Write([]byte{0})
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(at)))
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	_, err := NewSigner(nil)
+	assert.Error(t, err)
+	_, err = NewSigner([]byte("secret"), nil)
+	assert.Error(t, err)
+
+	oldSigner, err := NewSigner([]byte("old"))
+	require.NoError(t, err)
+	signer, err := NewSigner([]byte("secret"), []byte("old"))
+	require.NoError(t, err)
+	otherSigner, err := NewSigner([]byte("other"))
+	require.NoError(t, err)
+
+	payload := []byte(`{"node1":true}`)
+	signed := signer.sign(channelLED, payload)
+	got, err := signer.verify(channelLED, signed, signatureWindow)
+	require.NoError(t, err)
+	assert.Equal(t, payload, got)
+
+	// messages signed with an accepted key pass
+	got, err = signer.verify(channelLED, oldSigner.sign(channelLED, payload), signatureWindow)
+	require.NoError(t, err)
+	assert.Equal(t, payload, got)
+
+	// but the accepted key isn't used for signing
+	_, err = oldSigner.verify(channelLED, signed, signatureWindow)
+	assert.ErrorIs(t, err, errInvalidSignature)
+
+	_, err = signer.verify(channelLED, otherSigner.sign(channelLED, payload), signatureWindow)
+	assert.ErrorIs(t, err, errInvalidSignature)
+	_, err = signer.verify(channelNode, signed, signatureWindow)
+	assert.ErrorIs(t, err, errInvalidSignature)
+	tampered := append([]byte(nil), signed...)
+	tampered[len(tampered)-2] ^= 1
+	_, err = signer.verify(channelLED, tampered, signatureWindow)
+	assert.ErrorIs(t, err, errInvalidSignature)
+	_, err = signer.verify(channelLED, payload, signatureWindow)
+	assert.ErrorIs(t, err, errUnsigned)
+
+	// messages signed outside the window are rejected, unless the age isn't checked
+	old := signer.signAt(channelLED, payload, time.Now().Add(-2*signatureWindow))
+	_, err = signer.verify(channelLED, old, signatureWindow)
+	assert.ErrorIs(t, err, errExpired)
+	got, err = signer.verify(channelLED, old, 0)
+	require.NoError(t, err)
+	assert.Equal(t, payload, got)
+	_, err = signer.verify(channelLED, signer.signAt(channelLED, payload, time.Now().Add(2*signatureWindow)), signatureWindow)
+	assert.ErrorIs(t, err, errExpired)
+
+	// the signing time can't be changed without invalidating the signature
+	tampered = append([]byte(nil), old...)
+	copy(tampered[1+sha256.Size:signedHeaderSize], signed[1+sha256.Size:signedHeaderSize])
+	_, err = signer.verify(channelLED, tampered, signatureWindow)
+	assert.ErrorIs(t, err, errInvalidSignature)
+}
+
+func TestSigner_RedisEventHandler(t *testing.T) {
+	signer, err := NewSigner([]byte("secret"))
+	require.NoError(t, err)
+	payload := []byte(`{"node1":true}`)
+	signed := signer.sign(channelLED, payload)
+
+	var r redisEventHandler
+	// without a signer, signatures are removed, but not checked
+	unverified := testutil.ToFloat64(unverifiedEventsMetric.WithLabelValues(channelLED))
+	for _, msg := range [][]byte{payload, signed} {
+		got, err := r.open(channelLED, msg, signatureWindow)
+		require.NoError(t, err)
+		assert.Equal(t, payload, got)
+	}
+	assert.Equal(t, unverified+1, testutil.ToFloat64(unverifiedEventsMetric.WithLabelValues(channelLED)))
+	assert.Equal(t, payload, r.seal(channelLED, payload))
+
+	r.signer.Store(signer)
+	got, err := r.open(channelLED, r.seal(channelLED, payload), signatureWindow)
+	require.NoError(t, err)
+	assert.Equal(t, payload, got)
+	_, err = r.open(channelLED, payload, signatureWindow)
+	assert.ErrorIs(t, err, errUnsigned)
+	assert.Equal(t, "unsigned", rejectReason(err))
+	_, err = r.open(channelNode, signed, signatureWindow)
+	assert.Equal(t, "invalid_signature", rejectReason(err))
+	_, err = r.open(channelLED, signer.signAt(channelLED, payload, time.Now().Add(-time.Hour)), signatureWindow)
+	assert.Equal(t, "expired", rejectReason(err))
+}
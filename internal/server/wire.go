@@ -0,0 +1,296 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Encodings of the LED states a node can advertise in its NodeInfo.
+const (
+	// EncodingJSON sends the LED states as a bare JSON object, as the first versions did. All versions understand it,
+	// so it doesn't need to be advertised. It carries neither an apply time nor a header, so endpoints apply the states
+	// on arrival and don't fence them.
+	EncodingJSON = "json"
+	// EncodingEnvelope sends the LED states as a JSON stateUpdate, with the apply time and the header.
+	EncodingEnvelope = "json/v2"
+	// EncodingBinary sends the LED states as frames. See nodeTable and frame.
+	EncodingBinary = "binary/v1"
+)
+
+// supportedEncodings are the encodings this version can decode, besides EncodingJSON, in order of preference.
+var supportedEncodings = []string{EncodingBinary, EncodingEnvelope}
+
+// The binary encoding sends the node order once, as a nodeTable, and then sends the LED states as a frame, with one bit
+// per node. Binary messages start with the wire version, followed by the message type. The wire version is always
+// below 0x20, so binary messages can't be confused with JSON, which starts with '{' or '"'.
+const (
+	wireVersion byte = 1
+
+	messageNodeTable byte = 1
+	messageFrame     byte = 2
+)
+
+var (
+	errWireVersion = errors.New("unsupported wire version")
+	errTruncated   = errors.New("message truncated")
+	errUnknownType = errors.New("unknown message type")
+)
+
+// isBinary returns true if the payload is a binary message.
+func isBinary(payload []byte) bool {
+	return len(payload) > 0 && payload[0] < 0x20
+}
+
+// nodeTable holds the order of the nodes referenced by the frames of the leader's term. The ID is unique within the term.
+type nodeTable struct {
+	Leader string
+	Nodes  []string
+	Term   uint64
+	ID     uint64
+}
+
+// MarshalBinary encodes the nodeTable.
+func (t nodeTable) MarshalBinary() ([]byte, error) {
+	buf := []byte{wireVersion, messageNodeTable}
+	buf = binary.AppendUvarint(buf, t.Term)
+	buf = binary.AppendUvarint(buf, t.ID)
+	buf = appendString(buf, t.Leader)
+	buf = binary.AppendUvarint(buf, uint64(len(t.Nodes)))
+	for _, name := range t.Nodes {
+		buf = appendString(buf, name)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a nodeTable.
+func (t *nodeTable) UnmarshalBinary(data []byte) error {
+	r, err := newWireReader(data, messageNodeTable)
+	if err != nil {
+		return err
+	}
+	var table nodeTable
+	table.Term = r.uvarint()
+	table.ID = r.uvarint()
+	table.Leader = r.string()
+	count := r.uvarint()
+	if count > uint64(len(r.buf)) {
+		// every name takes at least one byte
+		return errTruncated
+	}
+	table.Nodes = make([]string, 0, count)
+	for range count {
+		table.Nodes = append(table.Nodes, r.string())
+	}
+	if r.err != nil {
+		return r.err
+	}
+	*t = table
+	return nil
+}
+
+// frame holds the LED states of the nodes in the nodeTable with the frame's term and table ID.
+type frame struct {
+	ApplyAt  time.Time
+	States   []bool
+	Term     uint64
+	Sequence uint64
+	TableID  uint64
+}
+
+// MarshalBinary encodes the frame.
+func (f frame) MarshalBinary() ([]byte, error) {
+	buf := []byte{wireVersion, messageFrame}
+	buf = binary.AppendUvarint(buf, f.Term)
+	buf = binary.AppendUvarint(buf, f.Sequence)
+	buf = binary.AppendUvarint(buf, f.TableID)
+	var applyAt int64
+	if !f.ApplyAt.IsZero() {
+		applyAt = f.ApplyAt.UnixNano()
+	}
+	buf = binary.AppendVarint(buf, applyAt)
+	buf = binary.AppendUvarint(buf, uint64(len(f.States)))
+	bits := make([]byte, (len(f.States)+7)/8)
+	for i, state := range f.States {
+		if state {
+			bits[i/8] |= 1 << (i % 8)
+		}
+	}
+	return append(buf, bits...), nil
+}
+
+// UnmarshalBinary decodes a frame.
+func (f *frame) UnmarshalBinary(data []byte) error {
+	r, err := newWireReader(data, messageFrame)
+	if err != nil {
+		return err
+	}
+	var fr frame
+	fr.Term = r.uvarint()
+	fr.Sequence = r.uvarint()
+	fr.TableID = r.uvarint()
+	if applyAt := r.varint(); applyAt != 0 {
+		fr.ApplyAt = time.Unix(0, applyAt).UTC()
+	}
+	count := r.uvarint()
+	if r.err != nil {
+		return r.err
+	}
+	// compare before rounding up: a huge count would overflow count+7.
+	if count > 8*uint64(len(r.buf)) || (count+7)/8 != uint64(len(r.buf)) {
+		return errTruncated
+	}
+	fr.States = make([]bool, count)
+	for i := range fr.States {
+		fr.States[i] = r.buf[i/8]&(1<<(i%8)) != 0
+	}
+	*f = fr
+	return nil
+}
+
+// stateUpdate returns the LED states of the frame, as a stateUpdate. Fails if the frame doesn't reference the table.
+func (f frame) stateUpdate(t nodeTable) (stateUpdate, error) {
+	if f.Term != t.Term || f.TableID != t.ID {
+		return stateUpdate{}, fmt.Errorf("unknown node table %d/%d", f.Term, f.TableID)
+	}
+	if len(f.States) != len(t.Nodes) {
+		return stateUpdate{}, fmt.Errorf("frame has %d states for %d nodes", len(f.States), len(t.Nodes))
+	}
+	states := make(ledStates, len(t.Nodes))
+	for i, name := range t.Nodes {
+		states[name] = f.States[i]
+	}
+	return stateUpdate{
+		ApplyAt: f.ApplyAt,
+		States:  states,
+		header:  header{Leader: t.Leader, Term: f.Term, Sequence: f.Sequence},
+	}, nil
+}
+
+// ledDecoder decodes the messages on the LED channel: JSON stateUpdates, node tables and frames. It keeps the last node
+// table, to decode the frames that follow it.
+type ledDecoder struct {
+	table nodeTable
+}
+
+// decode decodes a message. ok is false if the message doesn't carry LED states.
+func (d *ledDecoder) decode(payload []byte) (stateUpdate, bool, error) {
+	if !isBinary(payload) {
+		var update stateUpdate
+		err := json.Unmarshal(payload, &update)
+		return update, err == nil, err
+	}
+	if len(payload) < 2 {
+		return stateUpdate{}, false, errTruncated
+	}
+	if payload[0] != wireVersion {
+		return stateUpdate{}, false, fmt.Errorf("%w: %d", errWireVersion, payload[0])
+	}
+	switch payload[1] {
+	case messageNodeTable:
+		var t nodeTable
+		if err := t.UnmarshalBinary(payload); err != nil {
+			return stateUpdate{}, false, err
+		}
+		d.table = t
+		return stateUpdate{}, false, nil
+	case messageFrame:
+		var f frame
+		if err := f.UnmarshalBinary(payload); err != nil {
+			return stateUpdate{}, false, err
+		}
+		update, err := f.stateUpdate(d.table)
+		return update, err == nil, err
+	default:
+		return stateUpdate{}, false, fmt.Errorf("%w: %d", errUnknownType, payload[1])
+	}
+}
+
+// negotiateEncoding returns the preferred encoding that all nodes can decode. Nodes that don't advertise any encodings,
+// like versions that don't publish their NodeInfo, can only decode EncodingJSON.
+func negotiateEncoding(nodes []string, info func(string) (NodeInfo, bool)) string {
+	for _, encoding := range supportedEncodings {
+		if supports(nodes, info, encoding) {
+			return encoding
+		}
+	}
+	return EncodingJSON
+}
+
+// supports returns true if all nodes advertise the encoding.
+func supports(nodes []string, info func(string) (NodeInfo, bool), encoding string) bool {
+	for _, name := range nodes {
+		nodeInfo, ok := info(name)
+		if !ok || !slices.Contains(nodeInfo.Encodings, encoding) {
+			return false
+		}
+	}
+	return true
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// wireReader decodes the fields of a binary message. After the first error, all reads return zero values.
+type wireReader struct {
+	err error
+	buf []byte
+}
+
+func newWireReader(data []byte, messageType byte) (*wireReader, error) {
+	if len(data) < 2 {
+		return nil, errTruncated
+	}
+	if data[0] != wireVersion {
+		return nil, fmt.Errorf("%w: %d", errWireVersion, data[0])
+	}
+	if data[1] != messageType {
+		return nil, fmt.Errorf("%w: %d", errUnknownType, data[1])
+	}
+	return &wireReader{buf: data[2:]}, nil
+}
+
+func (r *wireReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *wireReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *wireReader) string() string {
+	length := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if length > uint64(len(r.buf)) {
+		r.err = errTruncated
+		return ""
+	}
+	s := string(r.buf[:length])
+	r.buf = r.buf[length:]
+	return s
+}
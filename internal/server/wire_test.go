@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeTable_Binary(t *testing.T) {
+	table := nodeTable{Leader: "node1", Nodes: []string{"node1", "node2", "node3"}, Term: 1_700_000_000_000, ID: 42}
+	payload, err := table.MarshalBinary()
+	require.NoError(t, err)
+	assert.True(t, isBinary(payload))
+
+	var got nodeTable
+	require.NoError(t, got.UnmarshalBinary(payload))
+	assert.Equal(t, table, got)
+
+	for i := range payload {
+		assert.Error(t, got.UnmarshalBinary(payload[:i]), i)
+	}
+}
+
+func TestFrame_Binary(t *testing.T) {
+	applyAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		frame frame
+	}{
+		{name: "empty", frame: frame{States: []bool{}, Term: 1, Sequence: 1, TableID: 1}},
+		{name: "immediate", frame: frame{States: []bool{true, false, true}, Term: 1, Sequence: 2, TableID: 1}},
+		{name: "timestamped", frame: frame{States: []bool{false, true}, Term: 1, Sequence: 3, TableID: 1, ApplyAt: applyAt}},
+		{name: "multiple bytes", frame: frame{States: []bool{true, false, false, false, false, false, false, false, false, true}, Term: 1, Sequence: 4, TableID: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := tt.frame.MarshalBinary()
+			require.NoError(t, err)
+			var got frame
+			require.NoError(t, got.UnmarshalBinary(payload))
+			assert.Equal(t, tt.frame, got)
+
+			assert.Error(t, got.UnmarshalBinary(payload[:len(payload)-1]))
+			assert.Error(t, got.UnmarshalBinary(append(payload, 0)))
+		})
+	}
+
+	t.Run("huge count", func(t *testing.T) {
+		payload := binary.AppendUvarint([]byte{wireVersion, messageFrame, 1, 1, 1, 0}, math.MaxUint64)
+		var got frame
+		assert.ErrorIs(t, got.UnmarshalBinary(payload), errTruncated)
+	})
+}
+
+func TestFrame_Size(t *testing.T) {
+	const count = 64
+	nodes := make([]string, count)
+	states := make([]bool, count)
+	update := stateUpdate{States: make(ledStates, count), header: header{Leader: "node0", Term: 1_700_000_000_000, Sequence: 1000}}
+	for i := range count {
+		nodes[i] = fmt.Sprintf("node%d", i)
+		states[i] = i%2 == 0
+		update.States[nodes[i]] = states[i]
+	}
+	jsonPayload, err := json.Marshal(update)
+	require.NoError(t, err)
+	binaryPayload, err := frame{States: states, Term: update.Term, Sequence: update.Sequence, TableID: 1}.MarshalBinary()
+	require.NoError(t, err)
+	assert.Less(t, len(binaryPayload)*20, len(jsonPayload))
+}
+
+func TestLedDecoder(t *testing.T) {
+	table := nodeTable{Leader: "leader", Nodes: []string{"node1", "node2"}, Term: 1, ID: 1}
+	tablePayload, _ := table.MarshalBinary()
+	framePayload, _ := frame{States: []bool{true, false}, Term: 1, Sequence: 2, TableID: 1}.MarshalBinary()
+	want := stateUpdate{
+		States: ledStates{"node1": true, "node2": false},
+		header: header{Leader: "leader", Term: 1, Sequence: 2},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var d ledDecoder
+		update, ok, err := d.decode([]byte(`{"node1":true}`))
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, stateUpdate{States: ledStates{"node1": true}}, update)
+	})
+
+	t.Run("table and frame", func(t *testing.T) {
+		var d ledDecoder
+		_, ok, err := d.decode(tablePayload)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		update, ok, err := d.decode(framePayload)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, want, update)
+	})
+
+	t.Run("loaded table", func(t *testing.T) {
+		d := ledDecoder{table: table}
+		update, ok, err := d.decode(framePayload)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, want, update)
+	})
+
+	t.Run("unknown table", func(t *testing.T) {
+		var d ledDecoder
+		_, ok, err := d.decode(framePayload)
+		assert.Error(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("wrong node count", func(t *testing.T) {
+		d := ledDecoder{table: nodeTable{Nodes: []string{"node1"}, Term: 1, ID: 1}}
+		_, _, err := d.decode(framePayload)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var d ledDecoder
+		for _, payload := range [][]byte{{wireVersion}, {wireVersion + 1, messageFrame}, {wireVersion, 0x1f}, []byte(`1`)} {
+			_, ok, err := d.decode(payload)
+			assert.Error(t, err)
+			assert.False(t, ok)
+		}
+	})
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	info := map[string]NodeInfo{
+		"node1": {Encodings: []string{EncodingBinary, EncodingEnvelope}},
+		"node2": {Encodings: []string{EncodingBinary, EncodingEnvelope}},
+		"node3": {Encodings: []string{EncodingEnvelope}},
+		"node4": {},
+	}
+	lookup := func(name string) (NodeInfo, bool) {
+		nodeInfo, ok := info[name]
+		return nodeInfo, ok
+	}
+	assert.Equal(t, EncodingBinary, negotiateEncoding([]string{"node1", "node2"}, lookup))
+	assert.Equal(t, EncodingEnvelope, negotiateEncoding([]string{"node1", "node3"}, lookup))
+	assert.Equal(t, EncodingJSON, negotiateEncoding([]string{"node1", "node4"}, lookup))
+	assert.Equal(t, EncodingJSON, negotiateEncoding([]string{"node1", "node5"}, lookup))
+}
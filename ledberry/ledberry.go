@@ -53,6 +53,11 @@ func (l *LED) Get() (bool, error) {
 	return brightness != 0, nil
 }
 
+// MaxBrightness returns the LED's maximum brightness.
+func (l *LED) MaxBrightness() int {
+	return l.maxBrightness
+}
+
 // GetModes returns the LED's supported trigger modes.
 func (l *LED) GetModes() iter.Seq[string] {
 	return maps.Keys(l.modes)
@@ -27,6 +27,8 @@ func TestLED_Get_Set(t *testing.T) {
 	value, err = l.Get()
 	require.NoError(t, err)
 	assert.False(t, value)
+
+	assert.Equal(t, 1, l.MaxBrightness())
 }
 
 func TestLED_GetModes(t *testing.T) {
@@ -3,14 +3,19 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/clambin/ledswitcher/elect"
 	"github.com/clambin/ledswitcher/internal/configuration"
@@ -19,7 +24,6 @@ import (
 	"github.com/clambin/ledswitcher/ledberry"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/redis/go-redis/v9"
 )
 
 var version = "change-me"
@@ -27,18 +31,37 @@ var version = "change-me"
 func main() {
 	ctx, done := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer done()
-	if err := run(ctx, configuration.GetConfiguration(), prometheus.DefaultRegisterer, version); err != nil {
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "check" {
+		cfg, err := configuration.Load(flag.CommandLine, os.Args[3:])
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "invalid configuration: %s\n", err.Error())
+			os.Exit(1)
+		}
+		if !checkConfiguration(ctx, cfg, os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+	cfg, err := configuration.GetConfiguration()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "invalid configuration: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if err = run(ctx, cfg, prometheus.DefaultRegisterer, version); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "failed to start: %s\n", err.Error())
 		os.Exit(1)
 	}
 }
 
 func run(ctx context.Context, cfg configuration.Configuration, r prometheus.Registerer, version string) error {
-	var opt slog.HandlerOptions
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	var level slog.LevelVar
 	if cfg.Debug {
-		opt.Level = slog.LevelDebug
+		level.Set(slog.LevelDebug)
 	}
-	logger := slog.New(slog.NewTextHandler(os.Stderr, &opt))
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: &level}))
 
 	logger.Info("starting ledswitcher", "version", version)
 	defer logger.Info("shutting down ledswitcher")
@@ -51,30 +74,46 @@ func run(ctx context.Context, cfg configuration.Configuration, r prometheus.Regi
 			}
 		}()
 	}
-	s, err := schedule.New(cfg.LeaderConfiguration.Scheduler.Mode)
+	settings, err := newSettings(cfg)
 	if err != nil {
-		return fmt.Errorf("schedule: %w", err)
+		return fmt.Errorf("invalid configuration: %w", err)
 	}
 	led, err := ledberry.New(cfg.EndpointConfiguration.LEDPath)
 	if err != nil {
 		return fmt.Errorf("led: %w", err)
 	}
+	client, err := cfg.RedisConfiguration.Client()
+	if err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	defer func() { _ = client.Close() }()
 
 	srv := server.NewServer(
 		cfg.NodeName,
-		s,
-		redis.NewClient(&redis.Options{
-			Addr:     cfg.RedisConfiguration.Addr,
-			Username: cfg.RedisConfiguration.Username,
-			Password: cfg.RedisConfiguration.Password,
-		}),
+		server.LocalNodeInfo(version, ledCapabilities(led), cfg.Labels),
+		nil,
+		client,
 		led,
 		cfg.LeaderConfiguration.Rotation,
-		10*time.Second,
-		time.Minute,
+		cfg.RegistryConfiguration.RegistrationInterval,
+		cfg.RegistryConfiguration.NodeExpiration,
+		cfg.RegistryConfiguration.CleanupInterval,
 		r,
 		logger,
 	)
+	if err = srv.SetDescriptor(settings.descriptor); err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	}
+	srv.SetNodeOrder(settings.order)
+	srv.SetLayout(server.Layout{Columns: cfg.LeaderConfiguration.Layout.Columns})
+	srv.SetProtocol(settings.protocol)
+	srv.SetLeadTime(cfg.LeaderConfiguration.LeadTime)
+	srv.SetFallback(settings.fallback)
+	srv.SetWarmUp(cfg.LeaderConfiguration.WarmUp, cfg.LeaderConfiguration.Quorum)
+	srv.SetCheckpoint(cfg.LeaderConfiguration.Checkpoint)
+	srv.SetSnapshot(cfg.RegistryConfiguration.Snapshot)
+	srv.SetSigner(settings.signer)
+	srv.SetGroup(cfg.Group)
 
 	if cfg.LeaderConfiguration.Leader != "" {
 		srv.SetLeader(cfg.LeaderConfiguration.Leader)
@@ -83,17 +122,32 @@ func run(ctx context.Context, cfg configuration.Configuration, r prometheus.Regi
 		go elect.RunOrDie(
 			ctx,
 			cfg.K8SConfiguration.Namespace,
-			cfg.K8SConfiguration.LockName,
+			cfg.LockName(),
 			cfg.NodeName,
 			func(identity string) { srv.SetLeader(identity) },
 			logger.With(slog.String("component", "k8s")),
 		)
 	}
 
+	if cfg.ConfigFile != "" {
+		w := configuration.Watcher{
+			OnChange: func(newCfg configuration.Configuration) error { return reconfigure(srv, &level, newCfg) },
+			Logger:   logger.With(slog.String("component", "configuration")),
+			Args:     os.Args[1:],
+		}
+		go func() { _ = w.Run(ctx, cfg.ConfigFile) }()
+	}
+
 	go func() {
 		mux := http.NewServeMux()
 		mux.Handle("/metrics", promhttp.Handler())
 		mux.Handle("/healthz", server.HealthHandler(srv))
+		mux.Handle("/status", server.StatusHandler(srv))
+		if token := cfg.APIConfiguration.Token; token != "" {
+			mux.Handle("/message", server.MessageHandler(srv, token))
+		} else {
+			logger.Debug("no api token: message API disabled")
+		}
 		logger.Debug("starting prometheus & health server", "addr", cfg.Addr)
 		if err := http.ListenAndServe(cfg.Addr, mux); !errors.Is(err, http.ErrServerClosed) {
 			logger.Error("failed to start prometheus server", "err", err)
@@ -102,3 +156,162 @@ func run(ctx context.Context, cfg configuration.Configuration, r prometheus.Regi
 
 	return srv.Run(ctx)
 }
+
+// reconfigure applies the settings of a reloaded configuration that can be changed while running: the LED pattern mode,
+// the node order and layout, the protocol, the rotation, the lead time, the fallback, the warm-up, snapshots, checkpoints,
+// the signing keys and the log level.
+func reconfigure(srv *server.Server, level *slog.LevelVar, cfg configuration.Configuration) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	settings, err := newSettings(cfg)
+	if err != nil {
+		return err
+	}
+	if err = srv.SetDescriptor(settings.descriptor); err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	}
+	srv.SetNodeOrder(settings.order)
+	srv.SetLayout(server.Layout{Columns: cfg.LeaderConfiguration.Layout.Columns})
+	srv.SetProtocol(settings.protocol)
+	srv.SetFallback(settings.fallback)
+	srv.SetWarmUp(cfg.LeaderConfiguration.WarmUp, cfg.LeaderConfiguration.Quorum)
+	srv.SetCheckpoint(cfg.LeaderConfiguration.Checkpoint)
+	srv.SetSnapshot(cfg.RegistryConfiguration.Snapshot)
+	srv.SetSigner(settings.signer)
+	srv.SetInterval(cfg.LeaderConfiguration.Rotation)
+	srv.SetLeadTime(cfg.LeaderConfiguration.LeadTime)
+	if cfg.Debug {
+		level.Set(slog.LevelDebug)
+	} else {
+		level.Set(slog.LevelInfo)
+	}
+	return nil
+}
+
+// settings are the parts of the configuration that are interpreted by the schedule or the server, converted to their types.
+type settings struct {
+	descriptor schedule.Descriptor
+	order      server.NodeOrder
+	protocol   server.Protocol
+	fallback   server.Fallback
+	signer     *server.Signer
+}
+
+// newSettings converts the configuration to the server's types. Like configuration.Validate, it reports all problems
+// found, rather than stopping at the first one.
+func newSettings(cfg configuration.Configuration) (settings, error) {
+	var s settings
+	var errs []error
+	var err error
+	if s.descriptor, err = newDescriptor(cfg.LeaderConfiguration.Scheduler); err != nil {
+		errs = append(errs, err)
+	}
+	if s.order, err = server.NewNodeOrder(cfg.LeaderConfiguration.Order.Mode, cfg.LeaderConfiguration.Order.Nodes); err != nil {
+		errs = append(errs, fmt.Errorf("order: %w", err))
+	}
+	if s.protocol, err = server.ParseProtocol(cfg.LeaderConfiguration.Protocol); err != nil {
+		errs = append(errs, fmt.Errorf("protocol: %w", err))
+	}
+	if s.fallback, err = newFallback(cfg); err != nil {
+		errs = append(errs, fmt.Errorf("fallback: %w", err))
+	}
+	if s.signer, err = newSigner(cfg.SigningConfiguration); err != nil {
+		errs = append(errs, fmt.Errorf("signing: %w", err))
+	}
+	return s, errors.Join(errs...)
+}
+
+func newDescriptor(cfg configuration.SchedulerConfiguration) (schedule.Descriptor, error) {
+	if _, err := schedule.New(cfg.Mode); err != nil {
+		return schedule.Descriptor{}, fmt.Errorf("mode: %w", err)
+	}
+	taps, err := schedule.ParseTaps(cfg.Taps)
+	if err != nil {
+		return schedule.Descriptor{}, fmt.Errorf("lfsr.taps: %w", err)
+	}
+	return schedule.Descriptor{
+		Mode:        cfg.Mode,
+		Message:     cfg.Message,
+		TimeZone:    cfg.TimeZone,
+		Taps:        taps,
+		MorseNode:   cfg.MorseNode,
+		Seed:        cfg.Seed,
+		Probability: cfg.Probability,
+		K:           cfg.K,
+	}, nil
+}
+
+// newFallback returns the Fallback for the configuration. The timeout is a number of rotations.
+func newFallback(cfg configuration.Configuration) (server.Fallback, error) {
+	mode, err := server.ParseFallbackMode(cfg.EndpointConfiguration.Fallback.Mode)
+	if err != nil {
+		return server.Fallback{}, err
+	}
+	return server.Fallback{
+		Mode:    mode,
+		Trigger: cfg.EndpointConfiguration.Fallback.Trigger,
+		Timeout: time.Duration(cfg.EndpointConfiguration.Fallback.Rotations) * cfg.LeaderConfiguration.Rotation,
+	}, nil
+}
+
+// newSigner returns the Signer for the configuration. If no key file is set, messages aren't signed and newSigner
+// returns nil.
+func newSigner(cfg configuration.SigningConfiguration) (*server.Signer, error) {
+	key, accepted, err := cfg.Keys()
+	if err != nil || key == nil {
+		return nil, err
+	}
+	return server.NewSigner(key, accepted...)
+}
+
+// ledCapabilities describes the node's LED. A ledberry.LED controls a single LED.
+func ledCapabilities(led *ledberry.LED) schedule.Capabilities {
+	return schedule.Capabilities{
+		LEDs:          1,
+		MaxBrightness: led.MaxBrightness(),
+		Triggers:      slices.Sorted(led.GetModes()),
+	}
+}
+
+// checkConfiguration validates the configuration, probes the LED and pings Redis. It writes a report to w and returns
+// true if all checks passed.
+func checkConfiguration(ctx context.Context, cfg configuration.Configuration, w io.Writer) bool {
+	checks := []struct {
+		check func() error
+		name  string
+	}{
+		{name: "configuration", check: func() error {
+			_, err := newSettings(cfg)
+			return errors.Join(cfg.Validate(), err)
+		}},
+		{name: "led " + cfg.EndpointConfiguration.LEDPath, check: func() error {
+			_, err := ledberry.New(cfg.EndpointConfiguration.LEDPath)
+			return err
+		}},
+		{name: "redis", check: func() error {
+			client, err := cfg.RedisConfiguration.Client()
+			if err != nil {
+				return err
+			}
+			defer func() { _ = client.Close() }()
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			return client.Ping(ctx).Err()
+		}},
+	}
+	ok := true
+	for _, c := range checks {
+		err := c.check()
+		if err == nil {
+			_, _ = fmt.Fprintf(w, "%s: OK\n", c.name)
+			continue
+		}
+		ok = false
+		_, _ = fmt.Fprintf(w, "%s: FAILED\n", c.name)
+		for line := range strings.SplitSeq(err.Error(), "\n") {
+			_, _ = fmt.Fprintf(w, "  - %s\n", line)
+		}
+	}
+	return ok
+}
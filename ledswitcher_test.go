@@ -1,12 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"testing"
 	"time"
 
 	"github.com/clambin/ledswitcher/internal/configuration"
+	"github.com/clambin/ledswitcher/internal/server"
 	servertest "github.com/clambin/ledswitcher/internal/testutils"
 	"github.com/clambin/ledswitcher/ledberry/testutils"
 	"github.com/stretchr/testify/assert"
@@ -36,6 +39,11 @@ func Test_runWithConfiguration(t *testing.T) {
 		EndpointConfiguration: configuration.EndpointConfiguration{
 			LEDPath: ledPath,
 		},
+		RegistryConfiguration: configuration.RegistryConfiguration{
+			RegistrationInterval: 10 * time.Second,
+			NodeExpiration:       time.Minute,
+			CleanupInterval:      10 * time.Minute,
+		},
 		RedisConfiguration: configuration.RedisConfiguration{Addr: addr},
 	}
 
@@ -55,3 +63,56 @@ func getHealth() error {
 	}
 	return nil
 }
+
+func Test_reconfigure(t *testing.T) {
+	srv := server.NewServer("localhost", server.NodeInfo{}, nil, nil, nil, time.Second, time.Second, time.Minute, 10*time.Minute, nil, slog.New(slog.DiscardHandler))
+	var level slog.LevelVar
+
+	cfg := configuration.Configuration{
+		Debug:    true,
+		Addr:     ":9090",
+		NodeName: "localhost",
+		LeaderConfiguration: configuration.LeaderConfiguration{
+			Leader:    "localhost",
+			Rotation:  time.Second,
+			Scheduler: configuration.SchedulerConfiguration{Mode: "binary"},
+		},
+		RegistryConfiguration: configuration.RegistryConfiguration{
+			RegistrationInterval: 10 * time.Second,
+			NodeExpiration:       time.Minute,
+			CleanupInterval:      10 * time.Minute,
+		},
+		EndpointConfiguration: configuration.EndpointConfiguration{LEDPath: "/sys/class/leds/led1"},
+		RedisConfiguration:    configuration.RedisConfiguration{Addr: "localhost:6379"},
+	}
+	require.NoError(t, reconfigure(srv, &level, cfg))
+	assert.Equal(t, slog.LevelDebug, level.Level())
+
+	cfg.LeaderConfiguration.Scheduler.Mode = "invalid"
+	assert.Error(t, reconfigure(srv, &level, cfg))
+
+	cfg.LeaderConfiguration.Scheduler.Mode = "linear"
+	cfg.LeaderConfiguration.Rotation = 0
+	assert.Error(t, reconfigure(srv, &level, cfg))
+}
+
+func Test_checkConfiguration(t *testing.T) {
+	ledPath := t.TempDir()
+	require.NoError(t, testutils.InitLED(ledPath))
+
+	cfg := configuration.Configuration{
+		Addr:                  ":9090",
+		NodeName:              "localhost",
+		EndpointConfiguration: configuration.EndpointConfiguration{LEDPath: ledPath},
+		LeaderConfiguration:   configuration.LeaderConfiguration{Leader: "localhost", Scheduler: configuration.SchedulerConfiguration{Mode: "invalid"}},
+		RedisConfiguration:    configuration.RedisConfiguration{Addr: "127.0.0.1:1"},
+	}
+
+	var output bytes.Buffer
+	assert.False(t, checkConfiguration(t.Context(), cfg, &output))
+	report := output.String()
+	assert.Contains(t, report, "configuration: FAILED\n  - rotation: must be positive (got 0s)\n")
+	assert.Contains(t, report, "  - mode: invalid schedule: invalid\n")
+	assert.Contains(t, report, "led "+ledPath+": OK\n")
+	assert.Contains(t, report, "redis: FAILED\n")
+}